@@ -3,6 +3,11 @@
 package geom
 
 // This file contains geometry that is specific to 2 dimensions.
+
+import (
+	"math"
+)
+
 // This assignment will fail for K != 2.
 var ensure2d [2]float64 = Vector{}
 
@@ -40,18 +45,343 @@ func (s Segment) Line() Line {
 	return Line{Origin: s[0], Normal: s.Normal()}
 }
 
+// Intersect returns the point at which two segments intersect, along with
+// the parametric positions of the point along each of the segments: 0 is the
+// first point of a segment and 1 is the second.  If the segments are
+// collinear and overlap, then the point of the overlap nearest to the
+// first point of the receiver is returned.  The last return value is true
+// if the segments intersect, and it is false if they do not.
+func (a Segment) Intersect(b Segment) (Point, float64, float64, bool) {
+	s, t, hit := linearIntersection(a[0], a[1].Minus(a[0]), 1, b[0], b[1].Minus(b[0]))
+	if !hit {
+		return Point{}, 0, 0, false
+	}
+	return a[0].Plus(a[1].Minus(a[0]).ScaledBy(s)), s, t, true
+}
+
+// SegmentIntersection returns the point at which the ray intersects a
+// segment, along with the distance along the ray to the point, and the
+// parametric position of the point along the segment: 0 is the first
+// point of the segment and 1 is the second.  If the ray is collinear with
+// the segment, then the nearest point of the segment along the ray is
+// returned.  The last return value is true if they intersect, and it is false
+// if they do not.
+func (r Ray) SegmentIntersection(s Segment) (Point, float64, float64, bool) {
+	d, t, hit := linearIntersection(r.Origin, r.Direction, math.Inf(1), s[0], s[1].Minus(s[0]))
+	if !hit {
+		return Point{}, 0, 0, false
+	}
+	return r.Origin.Plus(r.Direction.ScaledBy(d)), d, t, true
+}
+
+// SegmentHit returns the point at which the ray hits a segment.  The normal
+// of the hit is the normal of the segment, inverted if necessary to face the
+// origin of the ray.  The second return value is true if they intersect,
+// and it is false if they do not.
+func (r Ray) SegmentHit(s Segment) (RayHit, bool) {
+	p, d, _, hit := r.SegmentIntersection(s)
+	if !hit {
+		return RayHit{}, false
+	}
+	n := s.Normal()
+	if n.Dot(r.Direction) > 0 {
+		n = n.Inverse()
+	}
+	return RayHit{Distance: d, Point: p, Normal: n}, true
+}
+
+// CircleHit returns the first point at which the ray hits the edge of a
+// circle.  If the origin of the ray is inside of the circle, then the hit is
+// where the ray exits the circle.  The second return value is true if they
+// intersect, and it is false if they do not.
+func (r Ray) CircleHit(c Circle) (RayHit, bool) {
+	return r.SphereHit(Sphere(c))
+}
+
+// EllipseHit returns the points at which the ray enters and exits an
+// ellipse.  If the origin of the ray is inside of the ellipse, then the distance
+// to the entry is negative and both hits have Inside set.  The third return
+// value is true if they intersect, and it is false if they do not.
+func (r Ray) EllipseHit(e Ellipse) (RayHit, RayHit, bool) {
+	// Solve for the distances in the space where the ellipse is a unit
+	// circle at the origin.  The direction is not normalized in that
+	// space, so the roots are distances along the original ray.
+	o := Vector(r.Origin.Minus(e.Center))
+	for i := range o {
+		o[i] /= e.Radii[i]
+	}
+	d := r.Direction
+	for i := range d {
+		d[i] /= e.Radii[i]
+	}
+	a, b, c := d.Dot(d), 2*o.Dot(d), o.Dot(o)-1
+	disc := b*b - 4*a*c
+	if NearZero(a) || disc < 0 {
+		return RayHit{}, RayHit{}, false
+	}
+	sqrt := math.Sqrt(disc)
+	near, far := (-b-sqrt)/(2*a), (-b+sqrt)/(2*a)
+	if far < 0 {
+		return RayHit{}, RayHit{}, false
+	}
+	inside := near < 0
+	hit := func(t float64) RayHit {
+		p := r.Origin.Plus(r.Direction.ScaledBy(t))
+		n := p.Minus(e.Center)
+		for i := range n {
+			n[i] /= e.Radii[i] * e.Radii[i]
+		}
+		return RayHit{Distance: t, Point: p, Normal: n.Unit(), Inside: inside}
+	}
+	return hit(near), hit(far), true
+}
+
+// RectangleHit returns the first point at which the ray hits the boundary
+// of a rectangle.  If the origin of the ray is inside of the rectangle, then the
+// hit is where the ray exits the rectangle.  The second return value is true if
+// they intersect, and it is false if they do not.
+func (r Ray) RectangleHit(rect Rectangle) (RayHit, bool) {
+	rect = rect.Canon()
+	mn, mx := rect.Min, rect.Max()
+	near, far := math.Inf(-1), math.Inf(1)
+	nearAxis, farAxis := -1, -1
+	for i, di := range r.Direction {
+		if NearZero(di) {
+			if r.Origin[i] < mn[i] || r.Origin[i] > mx[i] {
+				return RayHit{}, false
+			}
+			continue
+		}
+		t0 := (mn[i] - r.Origin[i]) / di
+		t1 := (mx[i] - r.Origin[i]) / di
+		if t0 > t1 {
+			t0, t1 = t1, t0
+		}
+		if t0 > near {
+			near, nearAxis = t0, i
+		}
+		if t1 < far {
+			far, farAxis = t1, i
+		}
+	}
+	if near > far || far < 0 || nearAxis < 0 {
+		return RayHit{}, false
+	}
+	h := RayHit{Distance: near, Inside: near < 0}
+	axis, sign := nearAxis, -1.0
+	if h.Inside {
+		h.Distance, axis, sign = far, farAxis, 1
+	}
+	if r.Direction[axis] < 0 {
+		sign = -sign
+	}
+	h.Point = r.Origin.Plus(r.Direction.ScaledBy(h.Distance))
+	h.Normal[axis] = sign
+	return h, true
+}
+
+// linearIntersection returns the parameters u and t at which p+u*r
+// intersects q+t*s, with u in the range [0, uMax] and t in the range [0, 1].
+// If the two are collinear, the intersection with the smallest u is returned.
+// The last return value is true if there is an intersection, and it is false
+// if there is not.
+func linearIntersection(p Point, r Vector, uMax float64, q Point, s Vector) (float64, float64, bool) {
+	rMag, sMag := r.Magnitude(), s.Magnitude()
+	switch {
+	case NearZero(rMag) && NearZero(sMag):
+		return 0, 0, p.NearlyEquals(q)
+	case NearZero(rMag):
+		t := Clamp(s.Dot(p.Minus(q))/(sMag*sMag), 0, 1)
+		return 0, t, NearZero(q.Plus(s.ScaledBy(t)).Distance(p))
+	case NearZero(sMag):
+		u := Clamp(r.Dot(q.Minus(p))/(rMag*rMag), 0, uMax)
+		return u, 0, NearZero(p.Plus(r.ScaledBy(u)).Distance(q))
+	}
+
+	qp := q.Minus(p)
+	rxs := r.Cross(s)
+	if NearZero(rxs / (rMag * sMag)) {
+		if !NearZero(qp.Cross(r) / rMag) {
+			return 0, 0, false
+		}
+		// Collinear: find the overlap of q+t*s along p+u*r.
+		u0 := r.Dot(qp) / (rMag * rMag)
+		u1 := u0 + r.Dot(s)/(rMag*rMag)
+		lo := math.Max(0, math.Min(u0, u1))
+		hi := math.Min(uMax, math.Max(u0, u1))
+		if lo > hi+Threshold/rMag {
+			return 0, 0, false
+		}
+		t := Clamp(s.Dot(p.Plus(r.ScaledBy(lo)).Minus(q))/(sMag*sMag), 0, 1)
+		return lo, t, true
+	}
+
+	u := qp.Cross(s) / rxs
+	t := qp.Cross(r) / rxs
+	uTol, tTol := Threshold/rMag, Threshold/sMag
+	if u < -uTol || u > uMax+uTol || t < -tTol || t > 1+tTol {
+		return 0, 0, false
+	}
+	return Clamp(u, 0, uMax), Clamp(t, 0, 1), true
+}
+
+// Cross returns the magnitude of the cross product of two vectors,
+// which is positive if b is counter-clockwise from a.
+func (a Vector) Cross(b Vector) float64 {
+	return a[0]*b[1] - a[1]*b[0]
+}
+
 // A Circle is a 2-dimensional sphere.
 type Circle Sphere
 
 // An Ellipse is a 2-dimensional ellipsoid.
 type Ellipse Ellipsoid
 
+// A Capsule is the set of all points within a fixed distance of a segment.
+type Capsule struct {
+	Segment Segment
+	Radius  float64
+}
+
+// Bounds returns the smallest rectangle containing the capsule.
+func (c Capsule) Bounds() Rectangle {
+	return c.Segment.Bounds().Expand(c.Radius)
+}
+
+// NearestPoint returns the point in the capsule nearest to p.  If p is
+// inside of the capsule then p itself is returned.
+func (c Capsule) NearestPoint(p Point) Point {
+	q := c.Segment.NearestPoint(p)
+	d := p.Minus(q)
+	if d.SquaredMagnitude() <= c.Radius*c.Radius {
+		return p
+	}
+	return q.Plus(d.Unit().ScaledBy(c.Radius))
+}
+
+// Contains returns true if the point is within the ellipse or on its boundary.
+func (e Ellipse) Contains(p Point) bool {
+	d := p.Minus(e.Center)
+	for i := range d {
+		d[i] /= e.Radii[i]
+	}
+	return d.SquaredMagnitude() <= 1
+}
+
+// NearestPoint returns the point in the ellipse nearest to p.  If p is
+// inside of the ellipse then p itself is returned.
+func (e Ellipse) NearestPoint(p Point) Point {
+	if e.Contains(p) {
+		return p
+	}
+	// With y the point relative to the center and a the radii, the
+	// nearest point is x_i = a_i^2 y_i/(t+a_i^2), where t > 0 is the root
+	// of sum_i (a_i y_i/(t+a_i^2))^2 = 1.  The sum decreases as t grows,
+	// and it is at most 1 when t is |(a_0 y_0, a_1 y_1)|, so the root is
+	// found by bisection.
+	y := p.Minus(e.Center)
+	a := e.Radii
+	ay := a.Times(y)
+	sum := func(t float64) float64 {
+		s := 0.0
+		for i := range y {
+			r := ay[i] / (t + a[i]*a[i])
+			s += r * r
+		}
+		return s
+	}
+	lo, hi := 0.0, ay.Magnitude()
+	for i := 0; i < 200; i++ {
+		t := (lo + hi) / 2
+		if t == lo || t == hi {
+			break
+		}
+		if sum(t) > 1 {
+			lo = t
+		} else {
+			hi = t
+		}
+	}
+	var x Vector
+	for i := range x {
+		x[i] = a[i] * ay[i] / (hi + a[i]*a[i])
+	}
+	return e.Center.Plus(x)
+}
+
+// CapsuleHit returns the first point at which the ray hits the boundary
+// of a capsule.  If the origin of the ray is inside of the capsule, then the
+// hit is where the ray exits the capsule.  The second return value is true if
+// they intersect, and it is false if they do not.
+func (r Ray) CapsuleHit(c Capsule) (RayHit, bool) {
+	// The boundary of a capsule is made up of portions of the circles
+	// around its endpoints and two sides parallel to its segment.  Try each
+	// and keep the nearest hit that is actually on the boundary.
+	var ds []float64
+	for _, p := range c.Segment {
+		Q := p.Minus(r.Origin)
+		v := Q.Dot(r.Direction)
+		d := c.Radius*c.Radius - (Q.SquaredMagnitude() - v*v)
+		if d >= 0 {
+			ds = append(ds, v-math.Sqrt(d), v+math.Sqrt(d))
+		}
+	}
+	if !NearZero(c.Segment.Length()) {
+		n := c.Segment.Normal()
+		for _, k := range [...]float64{c.Radius, -c.Radius} {
+			side := Segment{c.Segment[0].Plus(n.ScaledBy(k)), c.Segment[1].Plus(n.ScaledBy(k))}
+			if _, d, _, hit := r.SegmentIntersection(side); hit {
+				ds = append(ds, d)
+			}
+		}
+	}
+
+	inside := r.Origin.SquaredDistance(c.Segment.NearestPoint(r.Origin)) < c.Radius*c.Radius
+	best := math.Inf(1)
+	for _, d := range ds {
+		if d < 0 || d >= best || inside && NearZero(d) {
+			continue
+		}
+		p := r.Origin.Plus(r.Direction.ScaledBy(d))
+		if math.Abs(p.Distance(c.Segment.NearestPoint(p))-c.Radius) > Threshold*math.Max(1, c.Radius) {
+			continue
+		}
+		best = d
+	}
+	if math.IsInf(best, 1) {
+		return RayHit{}, false
+	}
+	p := r.Origin.Plus(r.Direction.ScaledBy(best))
+	n := p.Minus(c.Segment.NearestPoint(p)).Unit()
+	return RayHit{Distance: best, Point: p, Normal: n, Inside: inside}, true
+}
+
 // A Rectangle represents a rectangular region of space.
 type Rectangle struct {
 	Min  Point
 	Size Vector
 }
 
+// NewRectangle returns the rectangle with two given points as opposite corners.
+func NewRectangle(a, b Point) Rectangle {
+	return Rectangle{Min: a, Size: b.Minus(a)}.Canon()
+}
+
+// BoundingBox returns the smallest rectangle containing all of the points.
+func BoundingBox(pts ...Point) Rectangle {
+	if len(pts) == 0 {
+		return Rectangle{}
+	}
+	mn, mx := pts[0], pts[0]
+	for _, p := range pts[1:] {
+		for i, pi := range p {
+			mn[i] = math.Min(mn[i], pi)
+			mx[i] = math.Max(mx[i], pi)
+		}
+	}
+	return Rectangle{Min: mn, Size: mx.Minus(mn)}
+}
+
 // Max returns the point on the rectangle with the maximum x and y values.
 func (r *Rectangle) Max() Point {
 	return r.Min.Plus(r.Size)
@@ -61,3 +391,150 @@ func (r *Rectangle) Max() Point {
 func (r *Rectangle) Center() Point {
 	return r.Min.Plus(r.Size.ScaledBy(0.5))
 }
+
+// Canon returns the canonical version of the rectangle, which has a
+// non-negative size along each axis.
+func (r Rectangle) Canon() Rectangle {
+	for i, si := range r.Size {
+		if si < 0 {
+			r.Min[i] += si
+			r.Size[i] = -si
+		}
+	}
+	return r
+}
+
+// Contains returns true if the point is within the rectangle or on its boundary.
+func (r Rectangle) Contains(p Point) bool {
+	mx := r.Max()
+	for i, pi := range p {
+		if pi < r.Min[i] || pi > mx[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ContainsRect returns true if the rectangle s is entirely within the receiver.
+func (r Rectangle) ContainsRect(s Rectangle) bool {
+	return r.Contains(s.Min) && r.Contains(s.Max())
+}
+
+// Intersects returns true if the rectangles have any points in common.
+func (r Rectangle) Intersects(s Rectangle) bool {
+	rMax, sMax := r.Max(), s.Max()
+	for i := range r.Min {
+		if r.Min[i] > sMax[i] || s.Min[i] > rMax[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Intersection returns the rectangle that is common to both rectangles.
+// The second return value is true if the rectangles intersect, and it is false
+// if they do not.
+func (r Rectangle) Intersection(s Rectangle) (Rectangle, bool) {
+	if !r.Intersects(s) {
+		return Rectangle{}, false
+	}
+	rMax, sMax := r.Max(), s.Max()
+	var mn, mx Point
+	for i := range r.Min {
+		mn[i] = math.Max(r.Min[i], s.Min[i])
+		mx[i] = math.Min(rMax[i], sMax[i])
+	}
+	return Rectangle{Min: mn, Size: mx.Minus(mn)}, true
+}
+
+// Union returns the smallest rectangle containing both rectangles.
+func (r Rectangle) Union(s Rectangle) Rectangle {
+	return BoundingBox(r.Min, r.Max(), s.Min, s.Max())
+}
+
+// Expand returns the rectangle grown by a distance on every side.
+func (r Rectangle) Expand(d float64) Rectangle {
+	r.Min.Add(Vector{-d, -d})
+	r.Size.Add(Vector{2 * d, 2 * d})
+	return r
+}
+
+// Inset returns the rectangle shrunk by a distance on every side.  If
+// the rectangle is too small to shrink by the distance along an axis, then
+// it is collapsed to its center along that axis.
+func (r Rectangle) Inset(d float64) Rectangle {
+	c := r.Center()
+	r = r.Expand(-d)
+	for i, si := range r.Size {
+		if si < 0 {
+			r.Min[i] = c[i]
+			r.Size[i] = 0
+		}
+	}
+	return r
+}
+
+// Clamp returns the point within the rectangle that is nearest to p.
+func (r Rectangle) Clamp(p Point) Point {
+	mx := r.Max()
+	for i, pi := range p {
+		p[i] = Clamp(pi, r.Min[i], mx[i])
+	}
+	return p
+}
+
+// Corners returns the corners of the rectangle in counter-clockwise
+// order, beginning with the minimum point.
+func (r Rectangle) Corners() [4]Point {
+	mn, mx := r.Min, r.Max()
+	return [4]Point{mn, {mx[0], mn[1]}, mx, {mn[0], mx[1]}}
+}
+
+// Polygon returns the rectangle as a polygon.
+func (r Rectangle) Polygon() Polygon {
+	cs := r.Corners()
+	return Polygon(cs[:])
+}
+
+// Edges returns the edges of the rectangle as segments with normals
+// pointing out of the rectangle.
+func (r Rectangle) Edges() []Segment {
+	return r.Polygon().Edges()
+}
+
+// A Region is a shape that encloses an area: a Rectangle, a Circle, an
+// Ellipse or a Polygon.  Polygons may be concave; their insides are
+// determined by the non-zero winding rule.  Other types cannot be Regions.
+type Region interface {
+	// Bounds returns the smallest rectangle containing the region.
+	Bounds() Rectangle
+
+	// Region does nothing; it restricts Regions to the types above.
+	region()
+}
+
+func (Rectangle) region() {}
+func (Circle) region()    {}
+func (Ellipse) region()   {}
+func (Polygon) region()   {}
+
+// Bounds returns the rectangle in canonical form, so that rectangles
+// can be used wherever a shape with bounds is expected.
+func (r Rectangle) Bounds() Rectangle {
+	return r.Canon()
+}
+
+// Bounds returns the smallest rectangle containing the segment.
+func (s Segment) Bounds() Rectangle {
+	return BoundingBox(s[0], s[1])
+}
+
+// Bounds returns the smallest rectangle containing the circle.
+func (c Circle) Bounds() Rectangle {
+	return Ellipse{Center: c.Center, Radii: Vector{c.Radius, c.Radius}}.Bounds()
+}
+
+// Bounds returns the smallest rectangle containing the ellipse.
+func (e Ellipse) Bounds() Rectangle {
+	return Rectangle{Min: e.Center.Plus(e.Radii.Inverse()), Size: e.Radii.ScaledBy(2)}.Canon()
+}
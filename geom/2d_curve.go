@@ -0,0 +1,270 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package geom
+
+// Curves in 2 dimensions.
+
+import (
+	"math"
+)
+
+// A Curve is a smooth parametric curve that runs from t=0 to t=1.
+type Curve interface {
+	// Point returns the point on the curve at t.
+	Point(t float64) Point
+
+	// Tangent returns the unit tangent of the curve at t, in the
+	// direction of increasing t.
+	Tangent(t float64) Vector
+
+	// Normal returns the unit normal of the curve at t.  As with the
+	// normal of a segment, it is the tangent rotated counter-clockwise.
+	Normal(t float64) Vector
+
+	// Bounds returns the smallest rectangle containing the curve.
+	Bounds() Rectangle
+
+	// Subdivide returns the parameters, in increasing order from 0 to 1,
+	// of points on the curve such that the curve between neighboring
+	// points is within a distance tol of the segment joining them.
+	Subdivide(tol float64) []float64
+
+	// Flatten returns the segments between the points given by Subdivide.
+	Flatten(tol float64) []Segment
+}
+
+// flatten returns the segments joining the points of a curve at parameters ts.
+func flatten(c Curve, ts []float64) []Segment {
+	segs := make([]Segment, len(ts)-1)
+	for i := range segs {
+		segs[i] = Segment{c.Point(ts[i]), c.Point(ts[i+1])}
+	}
+	return segs
+}
+
+// normal returns the unit vector rotated counter-clockwise from a unit tangent.
+func normal(t Vector) Vector {
+	return Vector{-t[1], t[0]}
+}
+
+// An Arc is a portion of a circle.  It runs from the Start angle to the End
+// angle, in radians, counter-clockwise if End is greater than Start and
+// clockwise if it is less.  The normals of a counter-clockwise arc point
+// toward its center, so the inside of a bowl is its front side.
+type Arc struct {
+	Center     Point
+	Radius     float64
+	Start, End float64
+}
+
+// angle returns the angle of the arc at t.
+func (a Arc) angle(t float64) float64 {
+	return a.Start + t*(a.End-a.Start)
+}
+
+// Point returns the point on the arc at t.
+func (a Arc) Point(t float64) Point {
+	sin, cos := math.Sincos(a.angle(t))
+	return Point{a.Center[0] + a.Radius*cos, a.Center[1] + a.Radius*sin}
+}
+
+// Tangent returns the unit tangent of the arc at t.
+func (a Arc) Tangent(t float64) Vector {
+	sin, cos := math.Sincos(a.angle(t))
+	if a.End < a.Start {
+		return Vector{sin, -cos}
+	}
+	return Vector{-sin, cos}
+}
+
+// Normal returns the unit normal of the arc at t.
+func (a Arc) Normal(t float64) Vector {
+	return normal(a.Tangent(t))
+}
+
+// Bounds returns the smallest rectangle containing the arc.
+func (a Arc) Bounds() Rectangle {
+	pts := []Point{a.Point(0), a.Point(1)}
+	lo, hi := math.Min(a.Start, a.End), math.Max(a.Start, a.End)
+	// The arc is extreme along an axis at multiples of pi/2, and all
+	// four extremes are found within a single turn.
+	for k := math.Ceil(lo / (math.Pi / 2)); k*math.Pi/2 <= hi && len(pts) < 6; k++ {
+		sin, cos := math.Sincos(k * math.Pi / 2)
+		pts = append(pts, Point{a.Center[0] + a.Radius*cos, a.Center[1] + a.Radius*sin})
+	}
+	return BoundingBox(pts...)
+}
+
+// Subdivide returns the parameters of evenly spaced points on the arc,
+// as described by Curve.  As with a Bezier, the arc is divided into at
+// most 2^maxSubdivisions pieces, however small tol is.
+func (a Arc) Subdivide(tol float64) []float64 {
+	n := float64(int(1) << maxSubdivisions)
+	if tol > 0 {
+		// A chord spanning an angle x is at most r(1-cos(x/2)) from the arc.
+		max := math.Pi
+		if tol < a.Radius {
+			max = 2 * math.Acos(1-tol/a.Radius)
+		}
+		n = math.Min(n, math.Max(1, math.Ceil(math.Abs(a.End-a.Start)/max)))
+	}
+	ts := make([]float64, int(n)+1)
+	for i := range ts {
+		ts[i] = float64(i) / n
+	}
+	return ts
+}
+
+// Flatten returns segments approximating the arc to within a distance tol.
+func (a Arc) Flatten(tol float64) []Segment {
+	return flatten(a, a.Subdivide(tol))
+}
+
+// A Bezier is a Bézier curve given by its control points: three for a
+// quadratic curve and four for a cubic.  The curve begins at the first
+// point and ends at the last.
+type Bezier []Point
+
+// Point returns the point on the curve at t.
+func (b Bezier) Point(t float64) Point {
+	pts := append([]Point(nil), b...)
+	for n := len(pts) - 1; n > 0; n-- {
+		for i := 0; i < n; i++ {
+			pts[i] = pts[i].Plus(pts[i+1].Minus(pts[i]).ScaledBy(t))
+		}
+	}
+	return pts[0]
+}
+
+// Derivative returns the derivative of the curve, which is a Bézier
+// curve of one less degree.  Its points are the derivative vectors.
+func (b Bezier) Derivative() Bezier {
+	n := len(b) - 1
+	d := make(Bezier, n)
+	for i := range d {
+		d[i] = Point(b[i+1].Minus(b[i]).ScaledBy(float64(n)))
+	}
+	return d
+}
+
+// Tangent returns the unit tangent of the curve at t.  If the derivative
+// is zero at t, because control points coincide, then the direction from
+// the first point to the last is returned.
+func (b Bezier) Tangent(t float64) Vector {
+	d := Vector(b.Derivative().Point(t))
+	if d.NearZero() {
+		return b[len(b)-1].Minus(b[0]).Unit()
+	}
+	return d.Unit()
+}
+
+// Normal returns the unit normal of the curve at t.
+func (b Bezier) Normal(t float64) Vector {
+	return normal(b.Tangent(t))
+}
+
+// Bounds returns the smallest rectangle containing a quadratic or cubic
+// curve.  For curves of higher degree, the rectangle containing the
+// control points is returned, which contains the curve but may be larger.
+func (b Bezier) Bounds() Rectangle {
+	if len(b) > 4 {
+		return BoundingBox(b...)
+	}
+	pts := []Point{b[0], b[len(b)-1]}
+	d := b.Derivative()
+	for i := range b[0] {
+		for _, t := range derivativeRoots(d, i) {
+			pts = append(pts, b.Point(t))
+		}
+	}
+	return BoundingBox(pts...)
+}
+
+// derivativeRoots returns the parameters between 0 and 1 at which
+// coordinate i of the derivative of a quadratic or cubic curve is zero.
+func derivativeRoots(d Bezier, i int) []float64 {
+	if len(d) < 2 {
+		return nil
+	}
+	// A linear derivative is raised to a quadratic with the same roots,
+	// then the roots of d0(1-t)^2 + 2d1(1-t)t + d2t^2 are found.
+	d0, d1, d2 := d[0][i], (d[0][i]+d[1][i])/2, d[1][i]
+	if len(d) == 3 {
+		d1, d2 = d[1][i], d[2][i]
+	}
+	a, b, c := d0-2*d1+d2, 2*(d1-d0), d0
+	var ts []float64
+	if NearZero(a) {
+		if !NearZero(b) {
+			ts = append(ts, -c/b)
+		}
+	} else if disc := b*b - 4*a*c; disc >= 0 {
+		sqrt := math.Sqrt(disc)
+		ts = append(ts, (-b-sqrt)/(2*a), (-b+sqrt)/(2*a))
+	}
+	roots := ts[:0]
+	for _, t := range ts {
+		if t > 0 && t < 1 {
+			roots = append(roots, t)
+		}
+	}
+	return roots
+}
+
+// Split returns two curves that together make up the receiver: the first
+// runs from 0 to t and the second from t to 1.
+func (b Bezier) Split(t float64) (Bezier, Bezier) {
+	n := len(b)
+	l, r := make(Bezier, n), make(Bezier, n)
+	pts := append([]Point(nil), b...)
+	for k := 0; k < n; k++ {
+		l[k], r[n-1-k] = pts[0], pts[n-1-k]
+		for i := 0; i < n-1-k; i++ {
+			pts[i] = pts[i].Plus(pts[i+1].Minus(pts[i]).ScaledBy(t))
+		}
+	}
+	return l, r
+}
+
+// maxSubdivisions is the maximum depth to which a curve is split
+// by Subdivide.
+const maxSubdivisions = 16
+
+// Subdivide returns the parameters of points on the curve as described
+// by Curve.  The curve is split in half until the control points of each
+// piece are within tol of the segment joining its end points, since the
+// curve lies within the convex hull of its control points.  As with an Arc,
+// if tol is not positive then the curve is split into 2^maxSubdivisions pieces.
+func (b Bezier) Subdivide(tol float64) []float64 {
+	ts := []float64{0}
+	var split func(b Bezier, t0, t1 float64, depth int)
+	split = func(b Bezier, t0, t1 float64, depth int) {
+		if depth == maxSubdivisions || tol > 0 && b.flat(tol) {
+			ts = append(ts, t1)
+			return
+		}
+		l, r := b.Split(0.5)
+		mid := (t0 + t1) / 2
+		split(l, t0, mid, depth+1)
+		split(r, mid, t1, depth+1)
+	}
+	split(b, 0, 1, 0)
+	return ts
+}
+
+// flat returns true if the control points of the curve are within
+// tol of the segment joining its end points.
+func (b Bezier) flat(tol float64) bool {
+	chord := Segment{b[0], b[len(b)-1]}
+	for _, p := range b[1 : len(b)-1] {
+		if chord.NearestPoint(p).SquaredDistance(p) > tol*tol {
+			return false
+		}
+	}
+	return true
+}
+
+// Flatten returns segments approximating the curve to within a distance tol.
+func (b Bezier) Flatten(tol float64) []Segment {
+	return flatten(b, b.Subdivide(tol))
+}
@@ -0,0 +1,147 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package geom
+
+import (
+	"math"
+	"testing"
+)
+
+var (
+	// Bowl is the bottom half of a circle, counter-clockwise.
+	bowl = Arc{Center: Point{0, 0}, Radius: 2, Start: math.Pi, End: 2 * math.Pi}
+
+	quad  = Bezier{{0, 0}, {1, 2}, {2, 0}}
+	cubic = Bezier{{0, 0}, {0, 1}, {3, 1}, {3, 0}}
+)
+
+func TestCurvePoint(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		c    Curve
+		t    float64
+		p    Point
+	}{
+		{"bowl", bowl, 0, Point{-2, 0}},
+		{"bowl", bowl, 0.5, Point{0, -2}},
+		{"bowl", bowl, 1, Point{2, 0}},
+		{"clockwise", Arc{Point{1, 1}, 1, math.Pi / 2, 0}, 1, Point{2, 1}},
+		{"quad", quad, 0, Point{0, 0}},
+		{"quad", quad, 0.5, Point{1, 1}},
+		{"quad", quad, 1, Point{2, 0}},
+		{"cubic", cubic, 0.5, Point{1.5, 0.75}},
+	}
+	for _, test := range tests {
+		p := test.c.Point(test.t)
+		if p.NearlyEquals(test.p) {
+			continue
+		}
+		t.Errorf("Expected %s at %g to be %v, got %v", test.name, test.t, test.p, p)
+	}
+}
+
+func TestCurveTangentNormal(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		c       Curve
+		t       float64
+		tangent Vector
+		normal  Vector
+	}{
+		{"bowl", bowl, 0.5, Vector{1, 0}, Vector{0, 1}},
+		{"bowl", bowl, 0, Vector{0, -1}, Vector{1, 0}},
+		{"clockwise", Arc{Point{0, 0}, 1, math.Pi / 2, 0}, 0, Vector{1, 0}, Vector{0, 1}},
+		{"quad", quad, 0.5, Vector{1, 0}, Vector{0, 1}},
+		{"quad", quad, 0, Vector{1, 2}.Unit(), Vector{-2, 1}.Unit()},
+		{"cubic", cubic, 0, Vector{0, 1}, Vector{-1, 0}},
+		{"cubic", cubic, 1, Vector{0, -1}, Vector{1, 0}},
+		{"coincident", Bezier{{0, 0}, {0, 0}, {1, 0}}, 0, Vector{1, 0}, Vector{0, 1}},
+	}
+	for _, test := range tests {
+		tan, n := test.c.Tangent(test.t), test.c.Normal(test.t)
+		if tan.NearlyEquals(test.tangent) && n.NearlyEquals(test.normal) {
+			continue
+		}
+		t.Errorf("Expected %s at %g to have tangent %v and normal %v, got %v and %v",
+			test.name, test.t, test.tangent, test.normal, tan, n)
+	}
+}
+
+func TestCurveBounds(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		c    Curve
+		r    Rectangle
+	}{
+		{"bowl", bowl, Rectangle{Point{-2, -2}, Vector{4, 2}}},
+		{"quarter", Arc{Point{0, 0}, 1, math.Pi / 4, 3 * math.Pi / 4}, Rectangle{Point{-math.Sqrt2 / 2, math.Sqrt2 / 2}, Vector{math.Sqrt2, 1 - math.Sqrt2/2}}},
+		{"circle", Arc{Point{1, 1}, 1, 0, -2 * math.Pi}, Rectangle{Point{0, 0}, Vector{2, 2}}},
+		{"quad", quad, Rectangle{Point{0, 0}, Vector{2, 1}}},
+		{"cubic", cubic, Rectangle{Point{0, 0}, Vector{3, 0.75}}},
+	}
+	for _, test := range tests {
+		r := test.c.Bounds()
+		if r.Min.NearlyEquals(test.r.Min) && r.Size.NearlyEquals(test.r.Size) {
+			continue
+		}
+		t.Errorf("Expected bounds of %s to be %v, got %v", test.name, test.r, r)
+	}
+}
+
+func TestBezierSplit(t *testing.T) {
+	t.Parallel()
+	for _, b := range []Bezier{quad, cubic} {
+		l, r := b.Split(0.25)
+		for _, s := range []float64{0, 0.3, 0.5, 1} {
+			if p := l.Point(s); !p.NearlyEquals(b.Point(0.25 * s)) {
+				t.Errorf("Expected left of %v at %g to be %v, got %v", b, s, b.Point(0.25*s), p)
+			}
+			if p := r.Point(s); !p.NearlyEquals(b.Point(0.25 + 0.75*s)) {
+				t.Errorf("Expected right of %v at %g to be %v, got %v", b, s, b.Point(0.25+0.75*s), p)
+			}
+		}
+	}
+}
+
+func TestCurveFlatten(t *testing.T) {
+	t.Parallel()
+	for _, c := range []Curve{bowl, quad, cubic} {
+		for _, tol := range []float64{0.5, 0.1, 0.001} {
+			ts := c.Subdivide(tol)
+			segs := c.Flatten(tol)
+			if ts[0] != 0 || ts[len(ts)-1] != 1 || len(segs) != len(ts)-1 {
+				t.Errorf("Expected %v subdivided at %g to run from 0 to 1, got %v", c, tol, ts)
+				continue
+			}
+			for i, s := range segs {
+				// Sample the curve between the ends of each segment.
+				for k := 0; k <= 10; k++ {
+					u := ts[i] + (ts[i+1]-ts[i])*float64(k)/10
+					p := c.Point(u)
+					if d := s.NearestPoint(p).Distance(p); d > tol+Threshold {
+						t.Errorf("Expected %v flattened at %g to be within %g, got %g at %g", c, tol, tol, d, u)
+					}
+				}
+			}
+		}
+	}
+	if n := len(bowl.Flatten(0.001)); n < len(bowl.Flatten(0.1)) {
+		t.Errorf("Expected a finer tolerance to give more segments")
+	}
+}
+
+func TestCurveSubdivideNonPositive(t *testing.T) {
+	t.Parallel()
+	for _, c := range []Curve{bowl, quad, cubic} {
+		for _, tol := range []float64{0, -1} {
+			ts := c.Subdivide(tol)
+			if len(ts) != 1<<maxSubdivisions+1 || ts[0] != 0 || ts[len(ts)-1] != 1 {
+				t.Errorf("Expected %v subdivided at %g to run from 0 to 1 in %d pieces, got %d points",
+					c, tol, 1<<maxSubdivisions, len(ts))
+			}
+		}
+	}
+}
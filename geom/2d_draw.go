@@ -127,6 +127,35 @@ func (e Ellipse) Draw(cv Canvas, cl color.Color) {
 	}
 }
 
+// Draw draws a capsule on the canvas.
+func (c Capsule) Draw(cv Canvas, cl color.Color) {
+	const N = 50
+	const dt = math.Pi / N
+
+	d := c.Segment[1].Minus(c.Segment[0])
+	if d.NearZero() {
+		Circle{Center: c.Segment[0], Radius: c.Radius}.Draw(cv, cl)
+		return
+	}
+	theta := math.Atan2(d[1], d[0])
+
+	// Trace the outline counter-clockwise: around the cap at the first
+	// end point, then the cap at the second end point, and the sides
+	// connect them.
+	var pts []Point
+	for i, p := range c.Segment {
+		t0 := theta + math.Pi/2 + float64(i)*math.Pi
+		for j := 0; j <= N; j++ {
+			t := t0 + float64(j)*dt
+			pts = append(pts, Point{p[0] + c.Radius*math.Cos(t), p[1] + c.Radius*math.Sin(t)})
+		}
+	}
+	for i := range pts {
+		p0, p1 := pts[i], pts[(i+1)%len(pts)]
+		cv.StrokeLine(cl, round(p0[0]), round(p0[1]), round(p1[0]), round(p1[1]))
+	}
+}
+
 // Draw draws a rectangle on the canvas.
 func (r Rectangle) Draw(cv Canvas, cl color.Color) {
 	mn, mx := r.Min, r.Max()
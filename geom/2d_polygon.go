@@ -0,0 +1,181 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package geom
+
+// Polygons in 2 dimensions.
+
+import (
+	"math"
+)
+
+// A Polygon is a closed shape given by an ordered list of its vertices.
+// The last vertex is implicitly connected to the first.
+type Polygon []Point
+
+// Edge returns the ith edge of the polygon, from the ith vertex to the next.
+func (poly Polygon) Edge(i int) Segment {
+	return Segment{poly[i], poly[(i+1)%len(poly)]}
+}
+
+// SignedArea returns the area of the polygon.  The area is positive if the
+// vertices are in counter-clockwise order, and it is negative if they are in
+// clockwise order.
+func (poly Polygon) SignedArea() float64 {
+	a := 0.0
+	for i := range poly {
+		e := poly.Edge(i)
+		a += Vector(e[0]).Cross(Vector(e[1]))
+	}
+	return a / 2
+}
+
+// Area returns the area of the polygon.
+func (poly Polygon) Area() float64 {
+	a := poly.SignedArea()
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// CounterClockwise returns true if the vertices of the polygon are
+// in counter-clockwise order.
+func (poly Polygon) CounterClockwise() bool {
+	return poly.SignedArea() > 0
+}
+
+// Reverse returns a polygon with the same vertices in the opposite order.
+func (poly Polygon) Reverse() Polygon {
+	rev := make(Polygon, len(poly))
+	for i, p := range poly {
+		rev[len(poly)-1-i] = p
+	}
+	return rev
+}
+
+// Centroid returns the center of mass of the polygon.  If the polygon
+// has no area then the average of its vertices is returned.
+func (poly Polygon) Centroid() Point {
+	a := poly.SignedArea()
+	if NearZero(a) {
+		c := Vector{}
+		for _, p := range poly {
+			c.Add(Vector(p))
+		}
+		return Point(c.ScaledBy(1 / float64(len(poly))))
+	}
+	c := Point{}
+	for i := range poly {
+		e := poly.Edge(i)
+		k := Vector(e[0]).Cross(Vector(e[1]))
+		c[0] += (e[0][0] + e[1][0]) * k
+		c[1] += (e[0][1] + e[1][1]) * k
+	}
+	c[0] /= 6 * a
+	c[1] /= 6 * a
+	return c
+}
+
+// Bounds returns the smallest rectangle containing the polygon.
+func (poly Polygon) Bounds() Rectangle {
+	return BoundingBox(poly...)
+}
+
+// Perimeter returns the total length of the edges of the polygon.
+func (poly Polygon) Perimeter() float64 {
+	l := 0.0
+	for i := range poly {
+		l += poly.Edge(i).Length()
+	}
+	return l
+}
+
+// Edges returns the edges of the polygon as segments.  The segments are
+// oriented so that their normals point out of the polygon, regardless
+// of the winding order of its vertices.
+func (poly Polygon) Edges() []Segment {
+	ccw := poly.CounterClockwise()
+	segs := make([]Segment, len(poly))
+	for i := range poly {
+		e := poly.Edge(i)
+		if ccw {
+			e[0], e[1] = e[1], e[0]
+		}
+		segs[i] = e
+	}
+	return segs
+}
+
+// Winding returns the winding number of the polygon around a point: the
+// number of times that the polygon travels counter-clockwise around it.
+func (poly Polygon) Winding(p Point) int {
+	w := 0
+	for i := range poly {
+		e := poly.Edge(i)
+		side := (e[1][0]-e[0][0])*(p[1]-e[0][1]) - (p[0]-e[0][0])*(e[1][1]-e[0][1])
+		switch {
+		case e[0][1] <= p[1] && e[1][1] > p[1] && side > 0:
+			w++
+		case e[0][1] > p[1] && e[1][1] <= p[1] && side < 0:
+			w--
+		}
+	}
+	return w
+}
+
+// ContainsNonZero returns true if the point is inside the polygon using
+// the non-zero rule: a point is inside if the polygon winds around it.
+func (poly Polygon) ContainsNonZero(p Point) bool {
+	return poly.Winding(p) != 0
+}
+
+// ContainsEvenOdd returns true if the point is inside the polygon using
+// the even-odd rule: a point is inside if a ray from it crosses the edges
+// of the polygon an odd number of times.
+func (poly Polygon) ContainsEvenOdd(p Point) bool {
+	in := false
+	for i := range poly {
+		e := poly.Edge(i)
+		if (e[0][1] > p[1]) == (e[1][1] > p[1]) {
+			continue
+		}
+		x := e[0][0] + (p[1]-e[0][1])*(e[1][0]-e[0][0])/(e[1][1]-e[0][1])
+		if p[0] < x {
+			in = !in
+		}
+	}
+	return in
+}
+
+// Convex returns true if the polygon is convex.  Collinear
+// vertices are allowed, but a polygon that winds around more
+// than once is not convex.
+func (poly Polygon) Convex() bool {
+	if len(poly) < 3 {
+		return false
+	}
+	sign := 0.0
+	turn := 0.0
+	for i := range poly {
+		a := poly.Edge(i)
+		b := poly.Edge((i + 1) % len(poly))
+		u, v := a[1].Minus(a[0]), b[1].Minus(b[0])
+		c := u.Cross(v)
+		if NearZero(c) {
+			continue
+		}
+		if sign == 0 {
+			sign = c
+		} else if (c > 0) != (sign > 0) {
+			return false
+		}
+		turn += angle(u, v)
+	}
+	// A convex polygon turns exactly once around.
+	return NearEqual(turn*turn, 4*math.Pi*math.Pi)
+}
+
+// angle returns the signed angle from u to v.
+func angle(u, v Vector) float64 {
+	return math.Atan2(u.Cross(v), u.Dot(v))
+}
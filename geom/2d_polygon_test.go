@@ -0,0 +1,177 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package geom
+
+import (
+	"testing"
+)
+
+var (
+	square = Polygon{{0, 0}, {2, 0}, {2, 2}, {0, 2}}
+	ell    = Polygon{{0, 0}, {2, 0}, {2, 1}, {1, 1}, {1, 2}, {0, 2}}
+
+	// Star is a pentagram, which winds twice around its center.
+	star = Polygon{{0, 3}, {2, -3}, {-3, 1}, {3, 1}, {-2, -3}}
+)
+
+func TestPolygonSignedArea(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		poly Polygon
+		area float64
+	}{
+		{square, 4},
+		{square.Reverse(), -4},
+		{ell, 3},
+		{ell.Reverse(), -3},
+		{Polygon{{0, 0}, {1, 0}, {0, 1}}, 0.5},
+		{Polygon{{0, 0}, {1, 1}}, 0},
+	}
+	for _, test := range tests {
+		a := test.poly.SignedArea()
+		if NearEqual(a, test.area) {
+			continue
+		}
+		t.Errorf("Expected signed area of %v to be %g, got %g", test.poly, test.area, a)
+	}
+}
+
+func TestPolygonCentroid(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		poly Polygon
+		c    Point
+	}{
+		{square, Point{1, 1}},
+		{square.Reverse(), Point{1, 1}},
+		{ell, Point{5.0 / 6, 5.0 / 6}},
+		{Polygon{{0, 0}, {2, 2}}, Point{1, 1}},
+	}
+	for _, test := range tests {
+		c := test.poly.Centroid()
+		if c.NearlyEquals(test.c) {
+			continue
+		}
+		t.Errorf("Expected centroid of %v to be %v, got %v", test.poly, test.c, c)
+	}
+}
+
+func TestPolygonPerimeter(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		poly Polygon
+		l    float64
+	}{
+		{square, 8},
+		{ell, 8},
+		{Polygon{{0, 0}, {3, 0}, {3, 4}}, 12},
+	}
+	for _, test := range tests {
+		l := test.poly.Perimeter()
+		if NearEqual(l, test.l) {
+			continue
+		}
+		t.Errorf("Expected perimeter of %v to be %g, got %g", test.poly, test.l, l)
+	}
+}
+
+func TestPolygonEdges(t *testing.T) {
+	t.Parallel()
+	for _, poly := range []Polygon{square, square.Reverse(), ell, ell.Reverse()} {
+		for _, e := range poly.Edges() {
+			out := e.Center().Plus(e.Normal().ScaledBy(0.1))
+			in := e.Center().Plus(e.Normal().ScaledBy(-0.1))
+			if poly.ContainsEvenOdd(out) || !poly.ContainsEvenOdd(in) {
+				t.Errorf("Expected the normal of edge %v of %v to point outward", e, poly)
+			}
+		}
+	}
+}
+
+func TestPolygonContains(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		poly             Polygon
+		p                Point
+		evenOdd, nonZero bool
+	}{
+		{square, Point{1, 1}, true, true},
+		{square.Reverse(), Point{1, 1}, true, true},
+		{square, Point{3, 1}, false, false},
+		{square, Point{-1, 1}, false, false},
+		{ell, Point{0.5, 1.5}, true, true},
+		{ell, Point{1.5, 1.5}, false, false},
+		{star, Point{0, 2}, true, true},
+		{star, Point{0, 0}, false, true},
+		{star, Point{0, 4}, false, false},
+	}
+	for _, test := range tests {
+		if eo := test.poly.ContainsEvenOdd(test.p); eo != test.evenOdd {
+			t.Errorf("Expected even-odd containment of %v in %v to be %t, got %t",
+				test.p, test.poly, test.evenOdd, eo)
+		}
+		if nz := test.poly.ContainsNonZero(test.p); nz != test.nonZero {
+			t.Errorf("Expected non-zero containment of %v in %v to be %t, got %t",
+				test.p, test.poly, test.nonZero, nz)
+		}
+	}
+}
+
+func TestPolygonWinding(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		poly Polygon
+		p    Point
+		w    int
+	}{
+		{square, Point{1, 1}, 1},
+		{square.Reverse(), Point{1, 1}, -1},
+		{square, Point{3, 1}, 0},
+		{star, Point{0, 0}, -2},
+		{star.Reverse(), Point{0, 0}, 2},
+	}
+	for _, test := range tests {
+		w := test.poly.Winding(test.p)
+		if w == test.w {
+			continue
+		}
+		t.Errorf("Expected winding number of %v around %v to be %d, got %d",
+			test.poly, test.p, test.w, w)
+	}
+}
+
+func TestPolygonConvex(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		poly   Polygon
+		convex bool
+	}{
+		{square, true},
+		{square.Reverse(), true},
+		{Polygon{{0, 0}, {1, 0}, {2, 0}, {2, 2}, {0, 2}}, true},
+		{ell, false},
+		{star, false},
+		{Polygon{{0, 0}, {1, 1}}, false},
+	}
+	for _, test := range tests {
+		c := test.poly.Convex()
+		if c == test.convex {
+			continue
+		}
+		t.Errorf("Expected convexity of %v to be %t, got %t", test.poly, test.convex, c)
+	}
+}
+
+func BenchmarkPolygonContainsNonZero(b *testing.B) {
+	p := Point{0.5, 1.5}
+	for i := 0; i < b.N; i++ {
+		ell.ContainsNonZero(p)
+	}
+}
+
+func BenchmarkPolygonContainsEvenOdd(b *testing.B) {
+	p := Point{0.5, 1.5}
+	for i := 0; i < b.N; i++ {
+		ell.ContainsEvenOdd(p)
+	}
+}
@@ -4,6 +4,7 @@ package geom
 
 import (
 	"image"
+	"math"
 	"testing"
 )
 
@@ -51,6 +52,24 @@ func TestSegmentNormal(t *testing.T) {
 	}
 }
 
+func TestVectorCross(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		a, b  Vector
+		cross float64
+	}{
+		{Vector{1, 0}, Vector{0, 1}, 1},
+		{Vector{0, 1}, Vector{1, 0}, -1},
+		{Vector{1, 0}, Vector{2, 0}, 0},
+		{Vector{2, 3}, Vector{4, 5}, -2},
+	}
+	for _, test := range tests {
+		if c := test.a.Cross(test.b); !NearEqual(c, test.cross) {
+			t.Errorf("Expected %v cross %v to be %f, got %f", test.a, test.b, test.cross, c)
+		}
+	}
+}
+
 func TestRectangleMax(t *testing.T) {
 	tests := []struct {
 		min  Point
@@ -89,6 +108,400 @@ func TestRectangleCenter(t *testing.T) {
 	}
 }
 
+func TestSegmentIntersect(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		a, b Segment
+		hit  bool
+		p    Point
+		s, t float64
+	}{
+		{Segment{{-1, 0}, {1, 0}}, Segment{{0, -1}, {0, 1}}, true, Point{0, 0}, 0.5, 0.5},
+		{Segment{{0, 0}, {2, 2}}, Segment{{0, 2}, {2, 0}}, true, Point{1, 1}, 0.5, 0.5},
+		{Segment{{0, 0}, {4, 0}}, Segment{{1, 1}, {1, -3}}, true, Point{1, 0}, 0.25, 0.25},
+		{Segment{{0, 0}, {1, 0}}, Segment{{1, 0}, {1, 1}}, true, Point{1, 0}, 1, 0},
+		{Segment{{0, 0}, {1, 0}}, Segment{{2, -1}, {2, 1}}, false, Point{}, 0, 0},
+		{Segment{{0, 0}, {1, 0}}, Segment{{0, 1}, {1, 1}}, false, Point{}, 0, 0},
+		{Segment{{0, 0}, {1, 0}}, Segment{{2, 0}, {3, 0}}, false, Point{}, 0, 0},
+		{Segment{{0, 0}, {2, 0}}, Segment{{1, 0}, {3, 0}}, true, Point{1, 0}, 0.5, 0},
+		{Segment{{0, 0}, {2, 0}}, Segment{{3, 0}, {1, 0}}, true, Point{1, 0}, 0.5, 1},
+		{Segment{{1, 0}, {3, 0}}, Segment{{0, 0}, {2, 0}}, true, Point{1, 0}, 0, 0.5},
+		{Segment{{1, 0}, {1, 0}}, Segment{{0, 0}, {2, 0}}, true, Point{1, 0}, 0, 0.5},
+		{Segment{{1, 1}, {1, 1}}, Segment{{0, 0}, {2, 0}}, false, Point{}, 0, 0},
+	}
+	for _, test := range tests {
+		p, s, u, hit := test.a.Intersect(test.b)
+		switch {
+		case hit != test.hit:
+			t.Errorf("Expected intersection of %v and %v to be %t, got %t", test.a, test.b, test.hit, hit)
+		case hit && (!p.NearlyEquals(test.p) || !NearEqual(s, test.s) || !NearEqual(u, test.t)):
+			t.Errorf("Expected %v and %v to intersect at %v (%g, %g), got %v (%g, %g)",
+				test.a, test.b, test.p, test.s, test.t, p, s, u)
+		}
+	}
+}
+
+func TestRaySegmentIntersection(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		r    Ray
+		s    Segment
+		hit  bool
+		d, t float64
+	}{
+		{Ray{Point{0, 0}, Vector{1, 0}}, Segment{{2, -1}, {2, 1}}, true, 2, 0.5},
+		{Ray{Point{0, 0}, Vector{-1, 0}}, Segment{{2, -1}, {2, 1}}, false, 0, 0},
+		{Ray{Point{0, 0}, Vector{1, 0}}, Segment{{2, 1}, {2, 3}}, false, 0, 0},
+		{Ray{Point{0, 0}, Vector{1, 0}}, Segment{{2, 0}, {4, 0}}, true, 2, 0},
+		{Ray{Point{0, 0}, Vector{1, 0}}, Segment{{4, 0}, {2, 0}}, true, 2, 1},
+		{Ray{Point{3, 0}, Vector{1, 0}}, Segment{{2, 0}, {4, 0}}, true, 0, 0.5},
+		{Ray{Point{5, 0}, Vector{1, 0}}, Segment{{2, 0}, {4, 0}}, false, 0, 0},
+		{Ray{Point{0, 0}, Vector{1, 1}.Unit()}, Segment{{0, 2}, {2, 0}}, true, math.Sqrt2, 0.5},
+	}
+	for _, test := range tests {
+		_, d, u, hit := test.r.SegmentIntersection(test.s)
+		switch {
+		case hit != test.hit:
+			t.Errorf("Expected intersection of %v and %v to be %t, got %t", test.r, test.s, test.hit, hit)
+		case hit && (!NearEqual(d, test.d) || !NearEqual(u, test.t)):
+			t.Errorf("Expected %v to hit %v at (%g, %g), got (%g, %g)", test.r, test.s, test.d, test.t, d, u)
+		}
+	}
+}
+
+func TestRayHit(t *testing.T) {
+	t.Parallel()
+	type hitter func(Ray) (RayHit, bool)
+	entry := func(e Ellipse) hitter {
+		return func(r Ray) (RayHit, bool) {
+			h, _, hit := r.EllipseHit(e)
+			return h, hit
+		}
+	}
+	exit := func(e Ellipse) hitter {
+		return func(r Ray) (RayHit, bool) {
+			_, h, hit := r.EllipseHit(e)
+			return h, hit
+		}
+	}
+	capsule := func(c Capsule) hitter {
+		return func(r Ray) (RayHit, bool) { return r.CapsuleHit(c) }
+	}
+	rect := Rectangle{Point{1, -1}, Vector{2, 2}}
+	tests := []struct {
+		name   string
+		r      Ray
+		f      hitter
+		hit    bool
+		d      float64
+		n      Vector
+		inside bool
+	}{
+		{"circle", Ray{Point{}, Vector{1, 0}}, func(r Ray) (RayHit, bool) {
+			return r.CircleHit(Circle{Point{0, 0}, 1})
+		}, true, 1, Vector{1, 0}, true},
+		{"segment", Ray{Point{}, Vector{1, 0}}, func(r Ray) (RayHit, bool) {
+			return r.SegmentHit(Segment{{1, -1}, {1, 1}})
+		}, true, 1, Vector{-1, 0}, false},
+		{"segment", Ray{Point{}, Vector{1, 0}}, func(r Ray) (RayHit, bool) {
+			return r.SegmentHit(Segment{{1, 1}, {1, -1}})
+		}, true, 1, Vector{-1, 0}, false},
+		{"segment", Ray{Point{}, Vector{-1, 0}}, func(r Ray) (RayHit, bool) {
+			return r.SegmentHit(Segment{{1, 1}, {1, -1}})
+		}, false, 0, Vector{}, false},
+		{"ellipse entry", Ray{Point{-3, 0}, Vector{1, 0}}, entry(Ellipse{Point{0, 0}, Vector{2, 1}}), true, 1, Vector{-1, 0}, false},
+		{"ellipse exit", Ray{Point{-3, 0}, Vector{1, 0}}, exit(Ellipse{Point{0, 0}, Vector{2, 1}}), true, 5, Vector{1, 0}, false},
+		{"ellipse entry", Ray{Point{0, -3}, Vector{0, 1}}, entry(Ellipse{Point{0, 0}, Vector{2, 1}}), true, 2, Vector{0, -1}, false},
+		{"ellipse entry", Ray{Point{0, 0}, Vector{0, 1}}, entry(Ellipse{Point{0, 0}, Vector{2, 1}}), true, -1, Vector{0, -1}, true},
+		{"ellipse exit", Ray{Point{0, 0}, Vector{0, 1}}, exit(Ellipse{Point{0, 0}, Vector{2, 1}}), true, 1, Vector{0, 1}, true},
+		{"ellipse", Ray{Point{0, 2}, Vector{1, 0}}, entry(Ellipse{Point{0, 0}, Vector{2, 1}}), false, 0, Vector{}, false},
+		{"ellipse", Ray{Point{3, 0}, Vector{1, 0}}, entry(Ellipse{Point{0, 0}, Vector{2, 1}}), false, 0, Vector{}, false},
+		{"capsule", Ray{Point{}, Vector{1, 0}}, capsule(Capsule{Segment{{2, 0}, {4, 0}}, 1}), true, 1, Vector{-1, 0}, false},
+		{"capsule", Ray{Point{3, -3}, Vector{0, 1}}, capsule(Capsule{Segment{{2, 0}, {4, 0}}, 1}), true, 2, Vector{0, -1}, false},
+		{"capsule", Ray{Point{3, 0}, Vector{0, 1}}, capsule(Capsule{Segment{{2, 0}, {4, 0}}, 1}), true, 1, Vector{0, 1}, true},
+		{"capsule", Ray{Point{3, 0}, Vector{1, 0}}, capsule(Capsule{Segment{{2, 0}, {4, 0}}, 1}), true, 2, Vector{1, 0}, true},
+		{"capsule", Ray{Point{0, 2}, Vector{1, 0}}, capsule(Capsule{Segment{{2, 0}, {4, 0}}, 1}), false, 0, Vector{}, false},
+		{"capsule", Ray{Point{0, 1}, Vector{1, 0}}, capsule(Capsule{Segment{{2, 0}, {4, 0}}, 1}), true, 2, Vector{0, 1}, false},
+		{"capsule", Ray{Point{1, 0}, Vector{1, 0}}, capsule(Capsule{Segment{{2, 0}, {2, 0}}, 1}), true, 0, Vector{-1, 0}, false},
+		{"rectangle", Ray{Point{}, Vector{1, 0}}, func(r Ray) (RayHit, bool) {
+			return r.RectangleHit(rect)
+		}, true, 1, Vector{-1, 0}, false},
+		{"rectangle", Ray{Point{2, -3}, Vector{0, 1}}, func(r Ray) (RayHit, bool) {
+			return r.RectangleHit(rect)
+		}, true, 2, Vector{0, -1}, false},
+		{"rectangle", Ray{Point{2, 0}, Vector{1, 0}}, func(r Ray) (RayHit, bool) {
+			return r.RectangleHit(rect)
+		}, true, 1, Vector{1, 0}, true},
+		{"rectangle", Ray{Point{2, 0}, Vector{0, -1}}, func(r Ray) (RayHit, bool) {
+			return r.RectangleHit(rect)
+		}, true, 1, Vector{0, -1}, true},
+		{"rectangle", Ray{Point{0, 0}, Vector{1, 1}.Unit()}, func(r Ray) (RayHit, bool) {
+			return r.RectangleHit(rect)
+		}, true, math.Sqrt2, Vector{-1, 0}, false},
+		{"rectangle", Ray{Point{0, 2}, Vector{1, 0}}, func(r Ray) (RayHit, bool) {
+			return r.RectangleHit(rect)
+		}, false, 0, Vector{}, false},
+		{"rectangle", Ray{Point{0, 0}, Vector{-1, 0}}, func(r Ray) (RayHit, bool) {
+			return r.RectangleHit(rect)
+		}, false, 0, Vector{}, false},
+	}
+	for _, test := range tests {
+		h, hit := test.f(test.r)
+		switch {
+		case hit != test.hit:
+			t.Errorf("Expected %v hitting %s to be %t, got %t", test.r, test.name, test.hit, hit)
+		case hit && (!NearEqual(h.Distance, test.d) || !h.Normal.NearlyEquals(test.n) || h.Inside != test.inside):
+			t.Errorf("Expected %v to hit %s at %g with normal %v and inside %t, got %+v",
+				test.r, test.name, test.d, test.n, test.inside, h)
+		}
+	}
+}
+
+func TestCapsuleNearestPoint(t *testing.T) {
+	t.Parallel()
+	c := Capsule{Segment{{0, 0}, {2, 0}}, 1}
+	tests := []struct {
+		p, n Point
+	}{
+		{Point{1, 0.5}, Point{1, 0.5}},
+		{Point{1, 3}, Point{1, 1}},
+		{Point{1, -3}, Point{1, -1}},
+		{Point{5, 0}, Point{3, 0}},
+		{Point{-3, 0}, Point{-1, 0}},
+	}
+	for _, test := range tests {
+		n := c.NearestPoint(test.p)
+		if n.NearlyEquals(test.n) {
+			continue
+		}
+		t.Errorf("Expected nearest point to %v in %v to be %v, got %v", test.p, c, test.n, n)
+	}
+}
+
+func TestEllipseContains(t *testing.T) {
+	t.Parallel()
+	e := Ellipse{Point{1, 1}, Vector{2, 1}}
+	tests := []struct {
+		p  Point
+		in bool
+	}{
+		{Point{1, 1}, true},
+		{Point{3, 1}, true},
+		{Point{1, 2}, true},
+		{Point{2.5, 1.5}, true},
+		{Point{3.1, 1}, false},
+		{Point{1, 2.1}, false},
+		{Point{2.8, 1.8}, false},
+	}
+	for _, test := range tests {
+		if in := e.Contains(test.p); in != test.in {
+			t.Errorf("Expected %v contains %v to be %t, got %t", e, test.p, test.in, in)
+		}
+	}
+}
+
+func TestEllipseNearestPoint(t *testing.T) {
+	t.Parallel()
+	e := Ellipse{Point{1, 1}, Vector{2, 1}}
+	tests := []struct {
+		p, n Point
+	}{
+		{Point{1.5, 1.5}, Point{1.5, 1.5}},
+		{Point{6, 1}, Point{3, 1}},
+		{Point{-4, 1}, Point{-1, 1}},
+		{Point{1, 4}, Point{1, 2}},
+		{Point{1, -2}, Point{1, 0}},
+	}
+	for _, test := range tests {
+		n := e.NearestPoint(test.p)
+		if n.NearlyEquals(test.n) {
+			continue
+		}
+		t.Errorf("Expected nearest point to %v in %v to be %v, got %v", test.p, e, test.n, n)
+	}
+
+	// Off of the axes, the nearest point is on the boundary and the
+	// vector to the point is normal to the boundary there.
+	for _, p := range []Point{{5, 5}, {-3, 4}, {0, -6}, {4, 1.5}} {
+		n := e.NearestPoint(p)
+		d := n.Minus(e.Center)
+		if !NearEqual(d[0]*d[0]/4+d[1]*d[1], 1) {
+			t.Errorf("Expected nearest point to %v in %v to be on the boundary, got %v", p, e, n)
+		}
+		normal := Vector{d[0] / 4, d[1]}
+		if !NearZero(normal.Cross(p.Minus(n))) {
+			t.Errorf("Expected nearest point to %v in %v to be along the normal, got %v", p, e, n)
+		}
+	}
+}
+
+func TestNewRectangle(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		a, b Point
+		r    Rectangle
+	}{
+		{Point{0, 0}, Point{1, 2}, Rectangle{Point{0, 0}, Vector{1, 2}}},
+		{Point{1, 2}, Point{0, 0}, Rectangle{Point{0, 0}, Vector{1, 2}}},
+		{Point{1, 0}, Point{0, 2}, Rectangle{Point{0, 0}, Vector{1, 2}}},
+	}
+	for _, test := range tests {
+		r := NewRectangle(test.a, test.b)
+		if r.Min.NearlyEquals(test.r.Min) && r.Size.NearlyEquals(test.r.Size) {
+			continue
+		}
+		t.Errorf("Expected rectangle with corners %v and %v to be %v, got %v", test.a, test.b, test.r, r)
+	}
+}
+
+func TestRectangleContains(t *testing.T) {
+	t.Parallel()
+	r := Rectangle{Point{0, 0}, Vector{2, 1}}
+	tests := []struct {
+		p  Point
+		in bool
+	}{
+		{Point{1, 0.5}, true},
+		{Point{0, 0}, true},
+		{Point{2, 1}, true},
+		{Point{3, 0.5}, false},
+		{Point{1, -0.5}, false},
+	}
+	for _, test := range tests {
+		in := r.Contains(test.p)
+		if in == test.in {
+			continue
+		}
+		t.Errorf("Expected containment of %v in %v to be %t, got %t", test.p, r, test.in, in)
+	}
+}
+
+func TestRectangleIntersection(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		a, b Rectangle
+		hit  bool
+		c    Rectangle
+	}{
+		{
+			Rectangle{Point{0, 0}, Vector{2, 2}},
+			Rectangle{Point{1, 1}, Vector{2, 2}},
+			true,
+			Rectangle{Point{1, 1}, Vector{1, 1}},
+		},
+		{
+			Rectangle{Point{0, 0}, Vector{4, 4}},
+			Rectangle{Point{1, 1}, Vector{1, 1}},
+			true,
+			Rectangle{Point{1, 1}, Vector{1, 1}},
+		},
+		{
+			Rectangle{Point{0, 0}, Vector{1, 1}},
+			Rectangle{Point{1, 0}, Vector{1, 1}},
+			true,
+			Rectangle{Point{1, 0}, Vector{0, 1}},
+		},
+		{
+			Rectangle{Point{0, 0}, Vector{1, 1}},
+			Rectangle{Point{2, 0}, Vector{1, 1}},
+			false,
+			Rectangle{},
+		},
+	}
+	for _, test := range tests {
+		if hit := test.a.Intersects(test.b); hit != test.hit {
+			t.Errorf("Expected intersection of %v and %v to be %t, got %t", test.a, test.b, test.hit, hit)
+		}
+		c, hit := test.a.Intersection(test.b)
+		if hit != test.hit || hit && (!c.Min.NearlyEquals(test.c.Min) || !c.Size.NearlyEquals(test.c.Size)) {
+			t.Errorf("Expected intersection of %v and %v to be %v, got %v", test.a, test.b, test.c, c)
+		}
+	}
+}
+
+func TestRectangleUnion(t *testing.T) {
+	t.Parallel()
+	a := Rectangle{Point{0, 0}, Vector{1, 1}}
+	b := Rectangle{Point{2, -1}, Vector{1, 1}}
+	u := a.Union(b)
+	if !u.Min.NearlyEquals(Point{0, -1}) || !u.Size.NearlyEquals(Vector{3, 2}) {
+		t.Errorf("Expected union of %v and %v to be %v, got %v", a, b, Rectangle{Point{0, -1}, Vector{3, 2}}, u)
+	}
+	if !u.ContainsRect(a) || !u.ContainsRect(b) {
+		t.Errorf("Expected union %v to contain %v and %v", u, a, b)
+	}
+}
+
+func TestRectangleInset(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		r Rectangle
+		d float64
+		s Rectangle
+	}{
+		{Rectangle{Point{0, 0}, Vector{4, 2}}, 0.5, Rectangle{Point{0.5, 0.5}, Vector{3, 1}}},
+		{Rectangle{Point{0, 0}, Vector{4, 2}}, 1.5, Rectangle{Point{1.5, 1}, Vector{1, 0}}},
+		{Rectangle{Point{0, 0}, Vector{4, 2}}, -1, Rectangle{Point{-1, -1}, Vector{6, 4}}},
+	}
+	for _, test := range tests {
+		s := test.r.Inset(test.d)
+		if s.Min.NearlyEquals(test.s.Min) && s.Size.NearlyEquals(test.s.Size) {
+			continue
+		}
+		t.Errorf("Expected %v inset by %g to be %v, got %v", test.r, test.d, test.s, s)
+	}
+}
+
+func TestRectangleClamp(t *testing.T) {
+	t.Parallel()
+	r := Rectangle{Point{0, 0}, Vector{2, 1}}
+	tests := []struct {
+		p, q Point
+	}{
+		{Point{1, 0.5}, Point{1, 0.5}},
+		{Point{-1, 0.5}, Point{0, 0.5}},
+		{Point{3, 3}, Point{2, 1}},
+	}
+	for _, test := range tests {
+		q := r.Clamp(test.p)
+		if q.NearlyEquals(test.q) {
+			continue
+		}
+		t.Errorf("Expected %v clamped to %v to be %v, got %v", test.p, r, test.q, q)
+	}
+}
+
+func TestRectangleEdges(t *testing.T) {
+	t.Parallel()
+	r := Rectangle{Point{0, 0}, Vector{2, 1}}
+	for _, e := range r.Edges() {
+		if r.Contains(e.Center().Plus(e.Normal())) {
+			t.Errorf("Expected the normal of edge %v of %v to point outward", e, r)
+		}
+	}
+}
+
+func TestBounds(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		b, r Rectangle
+	}{
+		{"rectangle", Rectangle{Point{2, 3}, Vector{-2, -3}}.Bounds(), Rectangle{Point{0, 0}, Vector{2, 3}}},
+		{"segment", Segment{{1, 0}, {0, 2}}.Bounds(), Rectangle{Point{0, 0}, Vector{1, 2}}},
+		{"circle", Circle{Point{1, 1}, 1}.Bounds(), Rectangle{Point{0, 0}, Vector{2, 2}}},
+		{"ellipse", Ellipse{Point{1, 1}, Vector{1, 2}}.Bounds(), Rectangle{Point{0, -1}, Vector{2, 4}}},
+		{"polygon", Polygon{{0, 0}, {2, 1}, {1, 3}}.Bounds(), Rectangle{Point{0, 0}, Vector{2, 3}}},
+		{"capsule", Capsule{Segment{{0, 0}, {2, 1}}, 1}.Bounds(), Rectangle{Point{-1, -1}, Vector{4, 3}}},
+	}
+	for _, test := range tests {
+		if test.b.Min.NearlyEquals(test.r.Min) && test.b.Size.NearlyEquals(test.r.Size) {
+			continue
+		}
+		t.Errorf("Expected %s bounds to be %v, got %v", test.name, test.r, test.b)
+	}
+}
+
 func BenchmarkLineDirection(b *testing.B) {
 	l := Line{Origin: Point{0, 0}, Normal: Vector{0, 1}}
 	for i := 0; i < b.N; i++ {
@@ -139,3 +552,19 @@ func BenchmarkRectangleCenter(b *testing.B) {
 		r.Center()
 	}
 }
+
+func BenchmarkSegmentIntersect(b *testing.B) {
+	s0 := Segment{Point{0, 0}, Point{2, 2}}
+	s1 := Segment{Point{0, 2}, Point{2, 0}}
+	for i := 0; i < b.N; i++ {
+		s0.Intersect(s1)
+	}
+}
+
+func BenchmarkRectangleIntersects(b *testing.B) {
+	r0 := Rectangle{Point{0, 0}, Vector{2, 2}}
+	r1 := Rectangle{Point{1, 1}, Vector{2, 2}}
+	for i := 0; i < b.N; i++ {
+		r0.Intersects(r1)
+	}
+}
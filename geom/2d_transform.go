@@ -0,0 +1,183 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package geom
+
+// Affine transformations of 2-dimensional primitives.
+
+import (
+	"math"
+)
+
+// A Transform is a 2-dimensional affine transformation.  It is
+// represented by the top two rows of a 3x3 matrix in homogeneous
+// coordinates; the bottom row is always 0, 0, 1.  A point (x, y) is
+// transformed to:
+//
+//	(t[0][0]*x + t[0][1]*y + t[0][2], t[1][0]*x + t[1][1]*y + t[1][2])
+type Transform [2][3]float64
+
+// Identity returns the transform that leaves everything unchanged.
+func Identity() Transform {
+	return Transform{
+		{1, 0, 0},
+		{0, 1, 0},
+	}
+}
+
+// Translate returns a transform that translates by a vector.
+func Translate(v Vector) Transform {
+	return Transform{
+		{1, 0, v[0]},
+		{0, 1, v[1]},
+	}
+}
+
+// Rotate returns a transform that rotates counter-clockwise about
+// the origin by an angle given in radians.
+func Rotate(theta float64) Transform {
+	sin, cos := math.Sincos(theta)
+	return Transform{
+		{cos, -sin, 0},
+		{sin, cos, 0},
+	}
+}
+
+// RotateAbout returns a transform that rotates counter-clockwise
+// about a point by an angle given in radians.
+func RotateAbout(p Point, theta float64) Transform {
+	o := Vector(p)
+	return Translate(o.Inverse()).Then(Rotate(theta)).Then(Translate(o))
+}
+
+// Scale returns a transform that scales each axis about the origin
+// by the corresponding component of a vector.
+func Scale(v Vector) Transform {
+	return Transform{
+		{v[0], 0, 0},
+		{0, v[1], 0},
+	}
+}
+
+// Shear returns a transform that shears about the origin.  The x
+// coordinate of a point is offset by v[0] times its y coordinate, and the
+// y coordinate is offset by v[1] times its x coordinate.
+func Shear(v Vector) Transform {
+	return Transform{
+		{1, v[0], 0},
+		{v[1], 1, 0},
+	}
+}
+
+// Then returns the transform that applies the receiver followed by b.
+func (a Transform) Then(b Transform) Transform {
+	var t Transform
+	for i := range t {
+		for j := 0; j < 3; j++ {
+			t[i][j] = b[i][0]*a[0][j] + b[i][1]*a[1][j]
+		}
+		t[i][2] += b[i][2]
+	}
+	return t
+}
+
+// Determinant returns the determinant of the linear portion of the transform.
+func (t Transform) Determinant() float64 {
+	return t[0][0]*t[1][1] - t[0][1]*t[1][0]
+}
+
+// Inverse returns the inverse of the transform.  The second return value
+// is true if the transform is invertible, and it is false if it is not.
+func (t Transform) Inverse() (Transform, bool) {
+	det := t.Determinant()
+	if NearZero(det) {
+		return Transform{}, false
+	}
+	a, b, c := t[0][0]/det, t[0][1]/det, t[0][2]
+	d, e, f := t[1][0]/det, t[1][1]/det, t[1][2]
+	return Transform{
+		{e, -b, b*f - e*c},
+		{-d, a, d*c - a*f},
+	}, true
+}
+
+// NearlyEquals returns true if the transforms are close enough to be considered equal.
+func (a Transform) NearlyEquals(b Transform) bool {
+	for i := range a {
+		for j, aij := range a[i] {
+			if !NearEqual(aij, b[i][j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ApplyPoint returns the transformed point.
+func (t Transform) ApplyPoint(p Point) Point {
+	return Point{
+		t[0][0]*p[0] + t[0][1]*p[1] + t[0][2],
+		t[1][0]*p[0] + t[1][1]*p[1] + t[1][2],
+	}
+}
+
+// ApplyVector returns the transformed vector.  Vectors are
+// unaffected by the translation portion of the transform.
+func (t Transform) ApplyVector(v Vector) Vector {
+	return Vector{
+		t[0][0]*v[0] + t[0][1]*v[1],
+		t[1][0]*v[0] + t[1][1]*v[1],
+	}
+}
+
+// ApplyNormal returns the transformed unit normal vector.  Normals are
+// transformed by the inverse transpose of the transform so that they remain
+// perpendicular to the transformed surface.
+func (t Transform) ApplyNormal(n Vector) Vector {
+	// The inverse transpose is the transposed cofactor matrix divided
+	// by the determinant.  Only the direction is needed, so divide by the
+	// magnitude instead, keeping the sign of the determinant so the normal
+	// stays on the same side of the surface when the transform reflects.
+	m := Vector{
+		t[1][1]*n[0] - t[1][0]*n[1],
+		-t[0][1]*n[0] + t[0][0]*n[1],
+	}
+	if t.Determinant() < 0 {
+		m = m.Inverse()
+	}
+	return m.Unit()
+}
+
+// ApplySegment returns the transformed segment.
+func (t Transform) ApplySegment(s Segment) Segment {
+	return Segment{t.ApplyPoint(s[0]), t.ApplyPoint(s[1])}
+}
+
+// ApplyRay returns the transformed ray.  The direction of the
+// resulting ray is a unit vector, so distances along the transformed
+// ray are only the same as distances along the original if the transform
+// preserves lengths.
+func (t Transform) ApplyRay(r Ray) Ray {
+	return Ray{
+		Origin:    t.ApplyPoint(r.Origin),
+		Direction: t.ApplyVector(r.Direction).Unit(),
+	}
+}
+
+// ApplyLine returns the transformed line.
+func (t Transform) ApplyLine(l Line) Line {
+	return Line{
+		Origin: t.ApplyPoint(l.Origin),
+		Normal: t.ApplyNormal(l.Normal),
+	}
+}
+
+// ApplyRectangle returns the smallest rectangle containing the
+// transformed rectangle.  If the transform only translates and scales,
+// this is exactly the transformed rectangle.
+func (t Transform) ApplyRectangle(r Rectangle) Rectangle {
+	cs := r.Corners()
+	for i, c := range cs {
+		cs[i] = t.ApplyPoint(c)
+	}
+	return BoundingBox(cs[:]...)
+}
@@ -0,0 +1,148 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package geom
+
+import (
+	"math"
+	"testing"
+	"testing/quick"
+)
+
+func TestTransformApplyPoint(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		t    Transform
+		p, q Point
+	}{
+		{Identity(), Point{1, 2}, Point{1, 2}},
+		{Translate(Vector{1, -1}), Point{1, 2}, Point{2, 1}},
+		{Rotate(math.Pi / 2), Point{1, 0}, Point{0, 1}},
+		{Rotate(math.Pi), Point{1, 2}, Point{-1, -2}},
+		{RotateAbout(Point{1, 1}, math.Pi/2), Point{2, 1}, Point{1, 2}},
+		{Scale(Vector{2, 3}), Point{1, 1}, Point{2, 3}},
+		{Shear(Vector{1, 0}), Point{1, 2}, Point{3, 2}},
+		{Shear(Vector{0, 1}), Point{1, 2}, Point{1, 3}},
+		{Translate(Vector{1, 0}).Then(Rotate(math.Pi / 2)), Point{0, 0}, Point{0, 1}},
+		{Rotate(math.Pi / 2).Then(Translate(Vector{1, 0})), Point{0, 0}, Point{1, 0}},
+		{Scale(Vector{2, 2}).Then(Translate(Vector{1, 1})), Point{1, 1}, Point{3, 3}},
+	}
+	for _, test := range tests {
+		q := test.t.ApplyPoint(test.p)
+		if q.NearlyEquals(test.q) {
+			continue
+		}
+		t.Errorf("Expected %v to transform %v to %v, got %v", test.t, test.p, test.q, q)
+	}
+}
+
+func TestTransformApplyVector(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		t    Transform
+		v, w Vector
+	}{
+		{Translate(Vector{1, -1}), Vector{1, 2}, Vector{1, 2}},
+		{Rotate(math.Pi / 2), Vector{1, 0}, Vector{0, 1}},
+		{Scale(Vector{2, 3}), Vector{1, 1}, Vector{2, 3}},
+	}
+	for _, test := range tests {
+		w := test.t.ApplyVector(test.v)
+		if w.NearlyEquals(test.w) {
+			continue
+		}
+		t.Errorf("Expected %v to transform %v to %v, got %v", test.t, test.v, test.w, w)
+	}
+}
+
+func TestTransformInverse(t *testing.T) {
+	t.Parallel()
+	err := quick.Check(func(v, s Vector, theta float64) bool {
+		s = s.Plus(Vector{1, 1})
+		tr := Scale(s).Then(Shear(v)).Then(Rotate(theta)).Then(Translate(v))
+		inv, ok := tr.Inverse()
+		return ok && tr.Then(inv).NearlyEquals(Identity()) && inv.Then(tr).NearlyEquals(Identity())
+	}, nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if _, ok := Scale(Vector{1, 0}).Inverse(); ok {
+		t.Errorf("Expected a degenerate scale to have no inverse")
+	}
+}
+
+func TestTransformApplyLine(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		t    Transform
+		l, m Line
+	}{
+		{
+			Rotate(math.Pi / 2),
+			Line{Point{1, 0}, Vector{1, 0}},
+			Line{Point{0, 1}, Vector{0, 1}},
+		},
+		{
+			Scale(Vector{2, 1}),
+			Segment{{0, 0}, {1, 1}}.Line(),
+			Segment{{0, 0}, {2, 1}}.Line(),
+		},
+		{
+			Scale(Vector{-1, 1}),
+			Line{Point{1, 1}, Vector{1, 0}},
+			Line{Point{-1, 1}, Vector{-1, 0}},
+		},
+	}
+	for _, test := range tests {
+		m := test.t.ApplyLine(test.l)
+		if m.Origin.NearlyEquals(test.m.Origin) && m.Normal.NearlyEquals(test.m.Normal) {
+			continue
+		}
+		t.Errorf("Expected %v to transform %v to %v, got %v", test.t, test.l, test.m, m)
+	}
+}
+
+func TestTransformApplyRectangle(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		t    Transform
+		r, s Rectangle
+	}{
+		{
+			Translate(Vector{1, 1}),
+			Rectangle{Point{0, 0}, Vector{1, 2}},
+			Rectangle{Point{1, 1}, Vector{1, 2}},
+		},
+		{
+			Scale(Vector{-1, 2}),
+			Rectangle{Point{0, 0}, Vector{1, 2}},
+			Rectangle{Point{-1, 0}, Vector{1, 4}},
+		},
+		{
+			Rotate(math.Pi / 4),
+			Rectangle{Point{0, 0}, Vector{1, 1}},
+			Rectangle{Point{-math.Sqrt2 / 2, 0}, Vector{math.Sqrt2, math.Sqrt2}},
+		},
+	}
+	for _, test := range tests {
+		s := test.t.ApplyRectangle(test.r)
+		if s.Min.NearlyEquals(test.s.Min) && s.Size.NearlyEquals(test.s.Size) {
+			continue
+		}
+		t.Errorf("Expected %v to transform %v to %v, got %v", test.t, test.r, test.s, s)
+	}
+}
+
+func BenchmarkTransformThen(b *testing.B) {
+	t0, t1 := Rotate(1), Translate(Vector{1, 1})
+	for i := 0; i < b.N; i++ {
+		t0.Then(t1)
+	}
+}
+
+func BenchmarkTransformApplyPoint(b *testing.B) {
+	t, p := Rotate(1), Point{1, 1}
+	for i := 0; i < b.N; i++ {
+		t.ApplyPoint(p)
+	}
+}
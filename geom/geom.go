@@ -42,6 +42,11 @@ func NearZero(f float64) bool {
 	return math.Abs(f) < Threshold
 }
 
+// Clamp returns the value nearest to f that is between min and max.
+func Clamp(f, min, max float64) float64 {
+	return math.Max(min, math.Min(max, f))
+}
+
 // A Point is a location in K-space.
 type Point [K]float64
 
@@ -249,6 +254,10 @@ func (r Ray) PlaneIntersection(p Plane) (float64, bool) {
 // SphereIntersection returns the distance along the ray at which it intersects a.
 // sphere. The second return value is true if they do intersect, and it is false if
 // they do not intersect.
+//
+// The distance is to the nearer of the two intersections of the line containing
+// the ray with the sphere, so it is negative if the origin of the ray is inside of
+// the sphere.  SphereHit reports that case explicitly.
 func (r Ray) SphereIntersection(s Sphere) (float64, bool) {
 	Q := s.Center.Minus(r.Origin)
 	c := Q.Magnitude()
@@ -260,6 +269,45 @@ func (r Ray) SphereIntersection(s Sphere) (float64, bool) {
 	return v - math.Sqrt(d), true
 }
 
+// A RayHit describes where a ray hits the surface of a shape.
+type RayHit struct {
+	// Distance is the distance along the ray to the hit.
+	Distance float64
+	// Point is the point on the surface of the shape that was hit.
+	Point Point
+	// Normal is the unit vector perpendicular to the surface at the
+	// hit point, pointing out of the shape.
+	Normal Vector
+	// Inside is true if the origin of the ray is inside of the shape.
+	Inside bool
+}
+
+// SphereHit returns the first point at which the ray hits the surface of a
+// sphere.  If the origin of the ray is inside of the sphere, then the hit is
+// where the ray exits the sphere.  The second return value is true if they
+// do intersect, and it is false if they do not intersect.
+func (r Ray) SphereHit(s Sphere) (RayHit, bool) {
+	Q := s.Center.Minus(r.Origin)
+	c := Q.Magnitude()
+	v := Q.Dot(r.Direction)
+	d := s.Radius*s.Radius - (c*c - v*v)
+	if d < 0 {
+		return RayHit{}, false
+	}
+	sqrt := math.Sqrt(d)
+	near, far := v-sqrt, v+sqrt
+	if far < 0 {
+		return RayHit{}, false
+	}
+	h := RayHit{Distance: near, Inside: near < 0}
+	if h.Inside {
+		h.Distance = far
+	}
+	h.Point = r.Origin.Plus(r.Direction.ScaledBy(h.Distance))
+	h.Normal = h.Point.Minus(s.Center).Unit()
+	return h, true
+}
+
 // A Segment is the portion of a line between and including two points.
 type Segment [2]Point
 
@@ -279,6 +327,9 @@ func (s Segment) Length() float64 {
 func (s Segment) NearestPoint(p Point) Point {
 	V := s[1].Minus(s[0])
 	d := V.Magnitude()
+	if NearZero(d) {
+		return s[0]
+	}
 	V = V.Unit()
 	t := V.Dot(p.Minus(s[0]))
 
@@ -52,6 +52,25 @@ func TestVectorDot(t *testing.T) {
 	}
 }
 
+func TestClamp(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		f, min, max, c float64
+	}{
+		{0.5, 0, 1, 0.5},
+		{-1, 0, 1, 0},
+		{2, 0, 1, 1},
+		{0, 0, 1, 0},
+		{1, 0, 1, 1},
+		{math.Inf(1), -5, 5, 5},
+	}
+	for _, test := range tests {
+		if c := Clamp(test.f, test.min, test.max); c != test.c {
+			t.Errorf("Expected %f clamped to [%f, %f] to be %f, got %f", test.f, test.min, test.max, test.c, c)
+		}
+	}
+}
+
 func TestVectorUnit(t *testing.T) {
 	t.Parallel()
 	err := quick.Check(func(v Vector) bool {
@@ -180,6 +199,34 @@ func TestRaySphereIntersectionMiss(t *testing.T) {
 	}
 }
 
+func TestRaySphereHit(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		r      Ray
+		s      Sphere
+		hit    bool
+		d      float64
+		n      Vector
+		inside bool
+	}{
+		{Ray{Point{}, Vector{1, 0}}, Sphere{Point{2, 0}, 1}, true, 1, Vector{-1, 0}, false},
+		{Ray{Point{}, Vector{1, 0}}, Sphere{Point{0, 0}, 1}, true, 1, Vector{1, 0}, true},
+		{Ray{Point{}, Vector{1, 0}}, Sphere{Point{0.5, 0}, 1}, true, 1.5, Vector{1, 0}, true},
+		{Ray{Point{}, Vector{-1, 0}}, Sphere{Point{2, 0}, 1}, false, 0, Vector{}, false},
+		{Ray{Point{}, Vector{0, 1}}, Sphere{Point{2, 0}, 1}, false, 0, Vector{}, false},
+	}
+	for _, test := range tests {
+		h, hit := test.r.SphereHit(test.s)
+		switch {
+		case hit != test.hit:
+			t.Errorf("Expected %v hitting %v to be %t, got %t", test.r, test.s, test.hit, hit)
+		case hit && (!NearEqual(h.Distance, test.d) || !h.Normal.NearlyEquals(test.n) || h.Inside != test.inside):
+			t.Errorf("Expected %v to hit %v at %g with normal %v and inside %t, got %+v",
+				test.r, test.s, test.d, test.n, test.inside, h)
+		}
+	}
+}
+
 func TestSegmentCenter(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -208,6 +255,7 @@ func TestSegmentNearestPoint(t *testing.T) {
 		{Point{-1, 0}, Point{1, 0}, Point{0, -1}, Point{0, 0}},
 		{Point{-1, -1}, Point{1, 1}, Point{-1, 1}, Point{0, 0}},
 		{Point{-1, -1}, Point{1, 1}, Point{1, -1}, Point{0, 0}},
+		{Point{1, 1}, Point{1, 1}, Point{2, 0}, Point{1, 1}},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,118 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"math"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+// A Hit is the first segment hit by a ray or by a shape swept along a vector.
+type Hit struct {
+	// Segment is the index of the segment that was hit.
+	Segment int
+
+	// Point is the point on the segment that was hit.
+	Point Point
+
+	// Normal is the unit normal of the hit, pointing from the point
+	// back toward the ray or the shape.  For the segments of a curve
+	// added with Grid.AddCurve, it is the normal of the curve.
+	Normal Vector
+
+	// Distance is the distance traveled before the hit.
+	Distance float64
+}
+
+// Raycast returns the first segment hit by a ray within a maximum
+// distance along it.  The ray's direction need not be a unit vector; the
+// distances are in the units of the segments.  One-way segments are only
+// hit from the front.  The second return value is false if nothing is hit.
+func Raycast(r Ray, maxDist float64, segs []Segment) (Hit, bool) {
+	return raycast(r, maxDist, segmentSlice(segs))
+}
+
+// CircleCast returns the first segment hit by a circle swept along a
+// vector, without moving the circle.  One-way segments are treated as
+// they are by MoveCircle.  The second return value is false if nothing
+// is hit.
+func CircleCast(c Circle, v Vector, segs []Segment) (Hit, bool) {
+	return circleCast(c, v, segmentSlice(segs))
+}
+
+// EllipseCast is like CircleCast, but it sweeps an ellipse.
+func EllipseCast(e Ellipse, v Vector, segs []Segment) (Hit, bool) {
+	return ellipseCast(e, v, segmentSlice(segs))
+}
+
+// Raycast is like the Raycast function, but it casts against the segments
+// of the grid.  The ray is cast no further than the edge of the grid's
+// segments, so maxDist may be infinite.
+func (g *Grid) Raycast(r Ray, maxDist float64) (Hit, bool) {
+	far := 0.0
+	for _, p := range g.bounds.Corners() {
+		far = math.Max(far, p.Distance(r.Origin))
+	}
+	// A hit on the farthest corner may round to just beyond it.
+	return raycast(r, math.Min(maxDist, far+Threshold), g)
+}
+
+// CircleCast is like the CircleCast function, but it casts against the
+// segments of the grid.
+func (g *Grid) CircleCast(c Circle, v Vector) (Hit, bool) {
+	return circleCast(c, v, g)
+}
+
+// EllipseCast is like the EllipseCast function, but it casts against the
+// segments of the grid.
+func (g *Grid) EllipseCast(e Ellipse, v Vector) (Hit, bool) {
+	return ellipseCast(e, v, g)
+}
+
+func raycast(r Ray, maxDist float64, segs segmentSet) (Hit, bool) {
+	if r.Direction.NearZero() || maxDist < 0 {
+		return Hit{}, false
+	}
+	r.Direction = r.Direction.Unit()
+	h := Hit{Segment: -1, Distance: math.Inf(1)}
+	box := BoundingBox(r.Origin, r.Origin.Plus(r.Direction.ScaledBy(maxDist)))
+	segs.near(box, func(i int, s Segment) {
+		if segs.props(i).oneWay && r.Direction.Dot(s.Normal()) >= 0 {
+			return
+		}
+		rh, hit := r.SegmentHit(s)
+		if !hit || rh.Distance > maxDist {
+			return
+		}
+		if c := segs.props(i).curve; c.curve != nil {
+			rh.Normal = c.normal(s, rh.Point, rh.Normal)
+		}
+		if rh.Distance < h.Distance || rh.Distance == h.Distance && i < h.Segment {
+			h = Hit{Segment: i, Point: rh.Point, Normal: rh.Normal, Distance: rh.Distance}
+		}
+	})
+	return h, !math.IsInf(h.Distance, 1)
+}
+
+func circleCast(c Circle, v Vector, segs segmentSet) (Hit, bool) {
+	if v.NearZero() {
+		return Hit{}, false
+	}
+	h, _, ok := firstHit(&circleBody{c}, v, segs, 0)
+	return h, ok
+}
+
+func ellipseCast(e Ellipse, v Vector, segs segmentSet) (Hit, bool) {
+	tr, inv := unitCircleSpace(e)
+	u := tr.ApplyVector(v)
+	c := Circle{Center: tr.ApplyPoint(e.Center), Radius: 1}
+	h, ok := circleCast(c, u, transformedSet{segs, tr, inv})
+	if !ok {
+		return Hit{}, false
+	}
+	h.Point = inv.ApplyPoint(h.Point)
+	h.Normal = inv.ApplyNormal(h.Normal)
+	h.Distance *= v.Magnitude() / u.Magnitude()
+	return h, true
+}
@@ -0,0 +1,187 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+func TestGridRaycast(t *testing.T) {
+	tests := []struct {
+		r       Ray
+		maxDist float64
+		hit     bool
+		seg     int
+		dist    float64
+	}{
+		{Ray{Origin: Point{50, 50}, Direction: Vector{0, -1}}, math.Inf(1), true, 0, 50},
+		{Ray{Origin: Point{50, 50}, Direction: Vector{0, -1}}, 1e300, true, 0, 50},
+		{Ray{Origin: Point{50, 50}, Direction: Vector{1, 0}}, 1e9, true, 1, 50},
+		{Ray{Origin: Point{50, 50}, Direction: Vector{1, 0}}, 49, false, 0, 0},
+		{Ray{Origin: Point{500, 50}, Direction: Vector{1, 0}}, 1e9, false, 0, 0},
+		// Segments that are not one-way are hit from behind.
+		{Ray{Origin: Point{500, 50}, Direction: Vector{-1, 0}}, 1e9, true, 1, 400},
+	}
+	g := NewGrid(box, 10)
+	for _, test := range tests {
+		h, hit := g.Raycast(test.r, test.maxDist)
+		if hit != test.hit {
+			t.Errorf("Expected %v cast %g to hit=%t, got %t", test.r, test.maxDist, test.hit, hit)
+			continue
+		}
+		if hit && (h.Segment != test.seg || !NearEqual(h.Distance, test.dist)) {
+			t.Errorf("Expected %v cast %g to hit segment %d at %g, got %d at %g",
+				test.r, test.maxDist, test.seg, test.dist, h.Segment, h.Distance)
+		}
+	}
+}
+
+// slope is a single segment, facing up and to the right.
+var slope = []Segment{{{0, 100}, {100, 0}}}
+
+func TestRaycast(t *testing.T) {
+	tests := []struct {
+		r       Ray
+		maxDist float64
+		hit     Hit
+		ok      bool
+	}{
+		{Ray{Origin: Point{50, 50}, Direction: Vector{0, -1}}, 100, Hit{0, Point{50, 0}, Vector{0, 1}, 50}, true},
+		// The direction need not be a unit vector.
+		{Ray{Origin: Point{50, 50}, Direction: Vector{0, -2}}, 100, Hit{0, Point{50, 0}, Vector{0, 1}, 50}, true},
+		{Ray{Origin: Point{50, 50}, Direction: Vector{0, -1}}, 50, Hit{0, Point{50, 0}, Vector{0, 1}, 50}, true},
+		{Ray{Origin: Point{50, 50}, Direction: Vector{0, -1}}, 49, Hit{}, false},
+		{Ray{Origin: Point{50, 50}, Direction: Vector{-1, 0}}, 100, Hit{3, Point{0, 50}, Vector{1, 0}, 50}, true},
+		// The lower index wins a tie at a corner.
+		{Ray{Origin: Point{50, 50}, Direction: Vector{1, -1}}, 100, Hit{0, Point{100, 0}, Vector{0, 1}, 50 * math.Sqrt2}, true},
+		{Ray{Origin: Point{50, 50}, Direction: Vector{}}, 100, Hit{}, false},
+		{Ray{Origin: Point{50, 50}, Direction: Vector{0, -1}}, -1, Hit{}, false},
+	}
+	g := NewGrid(box, 10)
+	for _, test := range tests {
+		h, ok := Raycast(test.r, test.maxDist, box)
+		if ok != test.ok || ok && !nearHit(h, test.hit) {
+			t.Errorf("Expected %v cast %g to hit %+v=%t, got %+v=%t", test.r, test.maxDist, test.hit, test.ok, h, ok)
+		}
+		gh, gok := g.Raycast(test.r, test.maxDist)
+		if gok != ok || gh != h {
+			t.Errorf("Expected %v cast %g in a grid to hit %+v=%t, got %+v=%t", test.r, test.maxDist, h, ok, gh, gok)
+		}
+	}
+}
+
+func TestCircleCast(t *testing.T) {
+	tests := []struct {
+		c    Circle
+		v    Vector
+		segs []Segment
+		hit  Hit
+		ok   bool
+	}{
+		{Circle{Center: Point{50, 50}, Radius: 10}, Vector{0, -100}, box, Hit{0, Point{50, 0}, Vector{0, 1}, 40}, true},
+		{Circle{Center: Point{50, 50}, Radius: 10}, Vector{0, -40}, box, Hit{0, Point{50, 0}, Vector{0, 1}, 40}, true},
+		{Circle{Center: Point{50, 50}, Radius: 10}, Vector{0, -30}, box, Hit{}, false},
+		{Circle{Center: Point{50, 50}, Radius: 10}, Vector{100, 0}, box, Hit{1, Point{100, 50}, Vector{-1, 0}, 40}, true},
+		{Circle{Center: Point{50, 50}, Radius: 10}, Vector{}, box, Hit{}, false},
+		// Circles only hit the front of a segment.
+		{Circle{Center: Point{50, 200}, Radius: 10}, Vector{0, -100}, box, Hit{}, false},
+		// The normal of a hit on the end of a segment points to the center.
+		{Circle{Center: Point{106, 20}, Radius: 10}, Vector{0, -100}, box[:1], Hit{0, Point{100, 0}, Vector{0.6, 0.8}, 12}, true},
+	}
+	for _, test := range tests {
+		h, ok := CircleCast(test.c, test.v, test.segs)
+		if ok != test.ok || ok && !nearHit(h, test.hit) {
+			t.Errorf("Expected %v cast %v to hit %+v=%t, got %+v=%t", test.c, test.v, test.hit, test.ok, h, ok)
+		}
+		gh, gok := NewGrid(test.segs, 10).CircleCast(test.c, test.v)
+		if gok != ok || gh != h {
+			t.Errorf("Expected %v cast %v in a grid to hit %+v=%t, got %+v=%t", test.c, test.v, h, ok, gh, gok)
+		}
+	}
+}
+
+func TestEllipseCast(t *testing.T) {
+	tests := []struct {
+		e    Ellipse
+		v    Vector
+		segs []Segment
+		hit  Hit
+		ok   bool
+	}{
+		{Ellipse{Center: Point{50, 50}, Radii: Vector{20, 10}}, Vector{0, -100}, box, Hit{0, Point{50, 0}, Vector{0, 1}, 40}, true},
+		{Ellipse{Center: Point{50, 50}, Radii: Vector{20, 10}}, Vector{0, -39}, box, Hit{}, false},
+		// The distances are in the units of the segments, not of the
+		// ellipse's unit circle.
+		{Ellipse{Center: Point{50, 50}, Radii: Vector{20, 10}}, Vector{100, 0}, box, Hit{1, Point{100, 50}, Vector{-1, 0}, 30}, true},
+		{Ellipse{Center: Point{50, 50}, Radii: Vector{20, 10}}, Vector{30, 0}, box, Hit{1, Point{100, 50}, Vector{-1, 0}, 30}, true},
+		{Ellipse{Center: Point{50, 50}, Radii: Vector{20, 10}}, Vector{29, 0}, box, Hit{}, false},
+		{Ellipse{Center: Point{50, 50}, Radii: Vector{20, 10}}, Vector{}, box, Hit{}, false},
+		// The normal is the slope's, not the normal of the slope in
+		// the ellipse's unit circle.
+		{
+			Ellipse{Center: Point{100, 100}, Radii: Vector{20, 10}},
+			Vector{-100, -100},
+			slope,
+			Hit{0, Point{43.29179606750063, 56.708203932499366}, Vector{1, 1}.Unit(), 54.899289817812864},
+			true,
+		},
+	}
+	for _, test := range tests {
+		h, ok := EllipseCast(test.e, test.v, test.segs)
+		if ok != test.ok || ok && !nearHit(h, test.hit) {
+			t.Errorf("Expected %v cast %v to hit %+v=%t, got %+v=%t", test.e, test.v, test.hit, test.ok, h, ok)
+		}
+		gh, gok := NewGrid(test.segs, 10).EllipseCast(test.e, test.v)
+		if gok != ok || ok && !nearHit(gh, h) {
+			t.Errorf("Expected %v cast %v in a grid to hit %+v=%t, got %+v=%t", test.e, test.v, h, ok, gh, gok)
+		}
+	}
+}
+
+func TestCastOneWay(t *testing.T) {
+	// The floor of the box is one-way, facing up, so casts from below
+	// pass through it to the ceiling.
+	g := NewGrid(box, 10)
+	g.SetOneWay(0, true)
+	up, down := Vector{0, 200}, Vector{0, -200}
+
+	if h, ok := g.Raycast(Ray{Origin: Point{50, -20}, Direction: up}, 200); !ok || h.Segment != 2 || !NearEqual(h.Distance, 120) {
+		t.Errorf("Expected a ray from below to pass through the one-way floor, got %+v=%t", h, ok)
+	}
+	if h, ok := g.Raycast(Ray{Origin: Point{50, 20}, Direction: down}, 200); !ok || h.Segment != 0 || !NearEqual(h.Distance, 20) {
+		t.Errorf("Expected a ray from above to hit the one-way floor, got %+v=%t", h, ok)
+	}
+
+	c := Circle{Center: Point{50, -20}, Radius: 5}
+	if h, ok := g.CircleCast(c, up); !ok || h.Segment != 2 || !NearEqual(h.Distance, 115) {
+		t.Errorf("Expected a circle from below to pass through the one-way floor, got %+v=%t", h, ok)
+	}
+	c.Center[1] = 20
+	if h, ok := g.CircleCast(c, down); !ok || h.Segment != 0 || !NearEqual(h.Distance, 15) {
+		t.Errorf("Expected a circle from above to hit the one-way floor, got %+v=%t", h, ok)
+	}
+	// A circle straddling the floor passes through it, as it does when moving.
+	c.Center[1] = 2
+	if h, ok := g.CircleCast(c, up); !ok || h.Segment != 2 || !NearEqual(h.Distance, 93) {
+		t.Errorf("Expected a circle straddling the one-way floor to pass through it, got %+v=%t", h, ok)
+	}
+
+	e := Ellipse{Center: Point{50, -20}, Radii: Vector{20, 10}}
+	if h, ok := g.EllipseCast(e, up); !ok || h.Segment != 2 || !NearEqual(h.Distance, 110) {
+		t.Errorf("Expected an ellipse from below to pass through the one-way floor, got %+v=%t", h, ok)
+	}
+	e.Center[1] = 20
+	if h, ok := g.EllipseCast(e, down); !ok || h.Segment != 0 || !NearEqual(h.Distance, 10) {
+		t.Errorf("Expected an ellipse from above to hit the one-way floor, got %+v=%t", h, ok)
+	}
+}
+
+// nearHit returns whether two hits are of the same segment, and nearly
+// at the same point, normal, and distance.
+func nearHit(a, b Hit) bool {
+	return a.Segment == b.Segment && a.Point.NearlyEquals(b.Point) &&
+		a.Normal.NearlyEquals(b.Normal) && NearEqual(a.Distance, b.Distance)
+}
@@ -0,0 +1,282 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"math"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+// A Character is an elliptical body that is controlled by a player or by
+// an AI, and that walks, jumps and falls like the hero of a platform game.
+//
+// Speeds are given in units per second, accelerations in units per second
+// per second, and times in seconds.  Directions are relative to the up
+// direction of the Options: moving right is moving clockwise from up.
+type Character struct {
+	// Body is the shape and position of the character.
+	Body Ellipse
+
+	// Velocity is the current velocity of the character.
+	Velocity Vector
+
+	// Grounded is true if the character was on the ground after its
+	// most recent step.
+	Grounded bool
+
+	// Asleep is true if the character has effectively stopped moving.
+	// A sleeping character is not moved by Step until it is given input,
+	// or until Wake is called, for example, because the segments changed.
+	Asleep bool
+
+	// Contacts are the contacts made during the most recent step.
+	Contacts []Contact
+
+	// Segments are the segments with which the character collides.
+	// If Grid is non-nil then its segments are used instead.
+	Segments []Segment
+	Grid     *Grid
+
+	// Options are the options used to move the character.
+	Options MoveOptions
+
+	// Gravity is the acceleration of the character toward the ground.
+	Gravity float64
+
+	// TerminalVelocity is the maximum speed at which the character falls.
+	TerminalVelocity float64
+
+	// RunSpeed is the maximum speed at which the character runs.
+	RunSpeed float64
+
+	// GroundAcceleration is the rate at which the character speeds up
+	// and slows down when running on the ground.
+	GroundAcceleration float64
+
+	// AirAcceleration is the rate at which the character speeds up and
+	// slows down horizontally while in the air.  If it is zero then the
+	// character has no control once it leaves the ground.
+	AirAcceleration float64
+
+	// JumpSpeed is the upward speed at the start of a jump.
+	JumpSpeed float64
+
+	// JumpCutoff is the fraction of its upward speed that the character
+	// keeps if the jump is released while still rising.  This gives
+	// short hops when jump is tapped and full jumps when it is held.
+	JumpCutoff float64
+
+	// CoyoteTime is how long after walking off of a ledge the character
+	// can still jump.
+	CoyoteTime float64
+
+	// JumpBuffer is how long before landing a jump press is remembered,
+	// so that pressing jump slightly too early still jumps on landing.
+	JumpBuffer float64
+
+	// StopSpeed is the speed below which a character on the ground with
+	// no input falls asleep.  Without it, a character standing on a slope
+	// would slowly creep down it.
+	StopSpeed float64
+
+	// coyote is the remaining coyote time.
+	coyote float64
+
+	// buffer is the time remaining for a buffered jump.
+	buffer float64
+
+	// jumping is true if the character is rising from a jump that
+	// has not yet been cut off.
+	jumping bool
+
+	// jumpHeld is true if jump was held during the previous step.
+	jumpHeld bool
+
+	// ground is the index of the segment that the character is
+	// standing on, if it is grounded.
+	ground int
+
+	// dropping are the indices of the one-way segments that the
+	// character is dropping through.
+	dropping []int
+}
+
+// Input is the control input for a single step of a Character.
+type Input struct {
+	// Move is the desired horizontal movement between -1, full speed
+	// to the left, and 1, full speed to the right.
+	Move float64
+
+	// Jump is true if the jump button is held.  A jump begins when
+	// it is pressed, and is cut off if it is released while rising.
+	Jump bool
+
+	// Down is true if the down button is held.  Pressing jump while
+	// holding down drops through a one-way segment instead of jumping.
+	Down bool
+}
+
+// NewCharacter returns a new character with the given body and default
+// parameters.
+func NewCharacter(body Ellipse, segs []Segment) *Character {
+	return &Character{
+		Body:               body,
+		Segments:           segs,
+		Gravity:            1500,
+		TerminalVelocity:   600,
+		RunSpeed:           150,
+		GroundAcceleration: 1500,
+		AirAcceleration:    600,
+		JumpSpeed:          550,
+		JumpCutoff:         0.5,
+		CoyoteTime:         0.1,
+		JumpBuffer:         0.1,
+		StopSpeed:          10,
+	}
+}
+
+// Wake wakes the character if it is asleep.
+func (c *Character) Wake() {
+	c.Asleep = false
+}
+
+// Step advances the character by dt seconds with the given input.
+// The error is as described for MoveEllipse; the character is left at
+// the last position that it reached.
+func (c *Character) Step(in Input, dt float64) error {
+	pressed := in.Jump && !c.jumpHeld
+	c.jumpHeld = in.Jump
+	if pressed {
+		c.buffer = c.JumpBuffer
+	} else {
+		c.buffer -= dt
+	}
+	if c.Grounded {
+		c.coyote = c.CoyoteTime
+	} else {
+		c.coyote -= dt
+	}
+
+	if c.Asleep {
+		if in.Move == 0 && !pressed {
+			c.Contacts = c.Contacts[:0]
+			return nil
+		}
+		c.Asleep = false
+	}
+
+	opts := c.Options.withDefaults()
+	up := opts.up(c.Body.Center)
+	right := Vector{up[1], -up[0]}
+	vx, vy := c.Velocity.Dot(right), c.Velocity.Dot(up)
+
+	// On the ground, running is relative to the surface,
+	// which may be moving, like a conveyor belt.
+	accel, surface := c.AirAcceleration, 0.0
+	if c.Grounded {
+		accel = c.GroundAcceleration
+		if g, ok := GroundContact(c.Contacts); ok {
+			surface = g.surfaceVelocity().Dot(right)
+		}
+	}
+	vx = approach(vx, surface+Clamp(in.Move, -1, 1)*c.RunSpeed, accel*dt)
+
+	if pressed && in.Down && c.Grounded && c.valid(c.ground) && c.segments().props(c.ground).oneWay {
+		c.dropping = append(c.dropping, c.ground)
+		c.buffer = 0
+		c.coyote = 0
+		c.Grounded = false
+	}
+	if c.buffer > 0 && c.coyote > 0 {
+		vy = c.JumpSpeed
+		c.buffer = 0
+		c.coyote = 0
+		c.jumping = true
+	}
+	if c.jumping && (vy <= 0 || !in.Jump) {
+		if vy > 0 {
+			vy *= c.JumpCutoff
+		}
+		c.jumping = false
+	}
+	vy = math.Max(vy-c.Gravity*dt, -c.TerminalVelocity)
+
+	v := right.ScaledBy(vx).Plus(up.ScaledBy(vy))
+	start := c.Body.Center
+	opts.Ignore = append(opts.Ignore[:len(opts.Ignore):len(opts.Ignore)], c.dropping...)
+	var err error
+	c.Body, c.Contacts, err = moveEllipse(c.Body, v.ScaledBy(dt), opts.filtered(c.segments()), opts)
+	c.clearDropping()
+
+	for _, ct := range c.Contacts {
+		v = ct.velocity(v)
+	}
+	c.Velocity = v
+	var ground Contact
+	ground, c.Grounded = GroundContact(c.Contacts)
+	if c.Grounded {
+		c.ground = ground.Segment
+		c.jumping = false
+		moved := c.Body.Center.Minus(start).Magnitude()
+		still := ground.surfaceVelocity().NearZero()
+		if in.Move == 0 && still && moved < c.StopSpeed*dt {
+			c.Velocity = Vector{}
+			c.Asleep = true
+		}
+	}
+	return err
+}
+
+// segments returns the segments with which the character collides.
+func (c *Character) segments() segmentSet {
+	if c.Grid != nil {
+		return c.Grid
+	}
+	return segmentSlice(c.Segments)
+}
+
+// clearDropping stops dropping through the segments that the
+// character no longer overlaps.
+func (c *Character) clearDropping() {
+	tr, _ := unitCircleSpace(c.Body)
+	unit := Circle{Center: tr.ApplyPoint(c.Body.Center), Radius: 1}
+	i := 0
+	for _, j := range c.dropping {
+		if !c.valid(j) {
+			continue
+		}
+		if _, _, ok := circlePenetration(unit, tr.ApplySegment(c.segment(j))); ok {
+			c.dropping[i] = j
+			i++
+		}
+	}
+	c.dropping = c.dropping[:i]
+}
+
+// valid returns true if i is the index of one of the character's segments.
+// The segments may have changed since the index was recorded, for example,
+// if the Grid was replaced.
+func (c *Character) valid(i int) bool {
+	n := len(c.Segments)
+	if c.Grid != nil {
+		n = len(c.Grid.segs)
+	}
+	return i >= 0 && i < n
+}
+
+// segment returns the segment with the given index.
+func (c *Character) segment(i int) Segment {
+	if c.Grid != nil {
+		return c.Grid.segs[i]
+	}
+	return c.Segments[i]
+}
+
+// approach returns x moved toward a target by at most d.
+func approach(x, target, d float64) float64 {
+	if x < target {
+		return math.Min(x+d, target)
+	}
+	return math.Max(x-d, target)
+}
@@ -0,0 +1,312 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"testing"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+const dt = 1.0 / 60
+
+// ledgeSegs are a floor and, above it, a one-way ledge.
+var ledgeSegs = []Segment{
+	{{0, 0}, {200, 0}},
+	{{0, 50}, {200, 50}},
+}
+
+func ledgeGrid() *Grid {
+	g := NewGrid(ledgeSegs, 20)
+	g.SetOneWay(1, true)
+	return g
+}
+
+func TestOneWay(t *testing.T) {
+	tests := []struct {
+		c       Circle
+		v       Vector
+		blocked bool
+	}{
+		// Falling onto the ledge from above.
+		{Circle{Center: Point{100, 70}, Radius: 10}, Vector{0, -40}, true},
+		{Circle{Center: Point{100, 70}, Radius: 10}, Vector{30, -40}, true},
+		// Jumping up through the ledge from below.
+		{Circle{Center: Point{100, 30}, Radius: 10}, Vector{0, 40}, false},
+		// Falling while overlapping the ledge.
+		{Circle{Center: Point{100, 55}, Radius: 10}, Vector{0, -40}, false},
+		// Moving along the ledge.
+		{Circle{Center: Point{100, 60}, Radius: 10}, Vector{40, 0}, false},
+	}
+	g := ledgeGrid()
+	for _, test := range tests {
+		_, cs, err := g.MoveCircle(test.c, test.v, nil)
+		if err != nil {
+			t.Errorf("Moving %v by %v: unexpected error %v", test.c, test.v, err)
+			continue
+		}
+		blocked := false
+		for _, c := range cs {
+			if c.Segment == 1 {
+				blocked = true
+			}
+		}
+		if blocked != test.blocked {
+			t.Errorf("Expected %v moving by %v to be blocked=%t by the one-way ledge, got %t",
+				test.c, test.v, test.blocked, blocked)
+		}
+	}
+}
+
+// stepCharacter steps a character n times with the given input,
+// failing the test on an error.
+func stepCharacter(t *testing.T, c *Character, in Input, n int) {
+	for i := 0; i < n; i++ {
+		if err := c.Step(in, dt); err != nil {
+			t.Fatalf("Step %d with %+v: unexpected error %v", i, in, err)
+		}
+	}
+}
+
+func TestCharacterDropThrough(t *testing.T) {
+	c := NewCharacter(Ellipse{Center: Point{100, 71}, Radii: Vector{10, 20}}, nil)
+	c.Grid = ledgeGrid()
+	stepCharacter(t, c, Input{}, 10)
+	if !c.Grounded || c.ground != 1 {
+		t.Fatalf("Expected to stand on the ledge, got grounded=%t on %d at %v", c.Grounded, c.ground, c.Body.Center)
+	}
+
+	stepCharacter(t, c, Input{Jump: true, Down: true}, 1)
+	stepCharacter(t, c, Input{}, 60)
+	if !c.Grounded || c.ground != 0 || !NearEqual(c.Body.Center[1], 20) {
+		t.Errorf("Expected to drop to the floor, got grounded=%t on %d at %v", c.Grounded, c.ground, c.Body.Center)
+	}
+	if len(c.dropping) != 0 {
+		t.Errorf("Expected to stop dropping below the ledge, still dropping through %v", c.dropping)
+	}
+
+	// Jump back up through the ledge and land on it.
+	stepCharacter(t, c, Input{Jump: true}, 60)
+	if !c.Grounded || c.ground != 1 {
+		t.Errorf("Expected to jump up onto the ledge, got grounded=%t on %d at %v", c.Grounded, c.ground, c.Body.Center)
+	}
+}
+
+func TestCharacterGridReplaced(t *testing.T) {
+	c := NewCharacter(Ellipse{Center: Point{100, 71}, Radii: Vector{10, 20}}, nil)
+	c.Grid = ledgeGrid()
+	stepCharacter(t, c, Input{}, 10)
+	stepCharacter(t, c, Input{Jump: true, Down: true}, 1)
+	if len(c.dropping) == 0 {
+		t.Fatalf("Expected to be dropping through the ledge")
+	}
+
+	// Replace the grid with one that has only the floor, while the
+	// character refers to the ledge, both standing on it and dropping
+	// through it.
+	c.Grid = NewGrid(ledgeSegs[:1], 20)
+	c.Grounded, c.ground = true, 1
+	stepCharacter(t, c, Input{Jump: true, Down: true}, 1)
+	stepCharacter(t, c, Input{}, 60)
+	if !c.Grounded || c.ground != 0 {
+		t.Errorf("Expected to land on the floor, got grounded=%t on %d at %v", c.Grounded, c.ground, c.Body.Center)
+	}
+}
+
+// standingCharacter returns a character that has settled onto the floor
+// of the ledge segments.
+func standingCharacter(t *testing.T, x float64) *Character {
+	c := NewCharacter(Ellipse{Center: Point{x, 20 + Threshold}, Radii: Vector{10, 20}}, ledgeSegs[:1])
+	stepCharacter(t, c, Input{}, 5)
+	if !c.Grounded {
+		t.Fatalf("Expected to stand on the floor, got %v", c.Body)
+	}
+	return c
+}
+
+func TestCharacterCoyoteTime(t *testing.T) {
+	tests := []struct {
+		coyote float64
+		// delay is the number of steps after leaving the floor
+		// before jump is pressed.
+		delay int
+		jumps bool
+	}{
+		{0.1, 1, true},
+		{0.1, 4, true},
+		{0.1, 10, false},
+		{0, 1, false},
+	}
+	for _, test := range tests {
+		// The character runs off of the right end of the floor.
+		c := standingCharacter(t, 180)
+		c.CoyoteTime = test.coyote
+		for i := 0; i < 60 && c.Grounded; i++ {
+			stepCharacter(t, c, Input{Move: 1}, 1)
+		}
+		if c.Grounded {
+			t.Fatalf("Expected to run off of the floor, got %v", c.Body)
+		}
+		stepCharacter(t, c, Input{Move: 1}, test.delay-1)
+		stepCharacter(t, c, Input{Move: 1, Jump: true}, 1)
+		if jumped := c.Velocity[1] > 0; jumped != test.jumps {
+			t.Errorf("Expected pressing jump %d steps after leaving the floor with coyote time %g to jump=%t, got %t",
+				test.delay, test.coyote, test.jumps, jumped)
+		}
+	}
+}
+
+func TestCharacterJumpBuffer(t *testing.T) {
+	body := Ellipse{Center: Point{100, 100}, Radii: Vector{10, 20}}
+	land := 0
+	for c := NewCharacter(body, ledgeSegs[:1]); !c.Grounded; land++ {
+		stepCharacter(t, c, Input{}, 1)
+	}
+
+	tests := []struct {
+		buffer float64
+		// early is the number of steps before landing that
+		// jump is pressed, and then held.
+		early int
+		jumps bool
+	}{
+		{0.1, 1, true},
+		{0.1, 4, true},
+		{0.1, 10, false},
+		{0, 1, false},
+	}
+	for _, test := range tests {
+		c := NewCharacter(body, ledgeSegs[:1])
+		c.JumpBuffer = test.buffer
+		stepCharacter(t, c, Input{}, land-test.early)
+		stepCharacter(t, c, Input{Jump: true}, test.early)
+		if !c.Grounded {
+			t.Fatalf("Expected to land after %d steps, got %v", land, c.Body)
+		}
+		stepCharacter(t, c, Input{Jump: true}, 1)
+		if jumped := c.Velocity[1] > 0; jumped != test.jumps {
+			t.Errorf("Expected pressing jump %d steps before landing with a buffer of %g to jump=%t, got %t",
+				test.early, test.buffer, test.jumps, jumped)
+		}
+	}
+}
+
+func TestCharacterJumpCutoff(t *testing.T) {
+	// Each step of gravity slows the character by 1500/60=25.
+	tests := []struct {
+		cutoff float64
+		held   int
+		vy     float64
+	}{
+		{0.5, 5, (550-5*25)*0.5 - 25},
+		{1, 5, 550 - 6*25},
+		{0, 5, -25},
+		// Releasing jump after the peak does not cut off the fall.
+		{0.5, 30, 550 - 31*25},
+	}
+	for _, test := range tests {
+		c := standingCharacter(t, 100)
+		c.JumpCutoff = test.cutoff
+		stepCharacter(t, c, Input{Jump: true}, test.held)
+		stepCharacter(t, c, Input{}, 1)
+		if !NearEqual(c.Velocity[1], test.vy) {
+			t.Errorf("Expected releasing jump after %d steps with a cutoff of %g to rise at %g, got %g",
+				test.held, test.cutoff, test.vy, c.Velocity[1])
+		}
+		// Releasing again does nothing.
+		vy := c.Velocity[1]
+		stepCharacter(t, c, Input{Jump: true}, 1)
+		stepCharacter(t, c, Input{}, 1)
+		if !NearEqual(c.Velocity[1], vy-2*25) {
+			t.Errorf("Expected to be cut off only once after %d steps with a cutoff of %g, got %g, then %g",
+				test.held, test.cutoff, vy, c.Velocity[1])
+		}
+	}
+}
+
+func TestCharacterAirControl(t *testing.T) {
+	tests := []struct {
+		air  float64
+		v    Vector
+		move float64
+		vx   float64
+	}{
+		{600, Vector{}, 1, 10},
+		{600, Vector{}, -1, -10},
+		{600, Vector{100, 0}, -1, 90},
+		{600, Vector{100, 0}, 0, 90},
+		{600, Vector{145, 0}, 1, 150},
+		// Without air acceleration, there is no control in the air.
+		{0, Vector{}, 1, 0},
+		{0, Vector{100, 0}, -1, 100},
+	}
+	for _, test := range tests {
+		c := NewCharacter(Ellipse{Center: Point{100, 500}, Radii: Vector{10, 20}}, ledgeSegs[:1])
+		c.AirAcceleration = test.air
+		c.Velocity = test.v
+		stepCharacter(t, c, Input{Move: test.move}, 1)
+		if !NearEqual(c.Velocity[0], test.vx) {
+			t.Errorf("Expected moving %g in the air at %v with acceleration %g to move at %g, got %g",
+				test.move, test.v, test.air, test.vx, c.Velocity[0])
+		}
+	}
+
+	// On the ground, the ground acceleration is used instead.
+	c := standingCharacter(t, 100)
+	c.AirAcceleration = 0
+	stepCharacter(t, c, Input{Move: 1}, 1)
+	if !NearEqual(c.Velocity[0], 25) {
+		t.Errorf("Expected moving on the ground to move at 25, got %g", c.Velocity[0])
+	}
+}
+
+func TestCharacterSleep(t *testing.T) {
+	c := standingCharacter(t, 100)
+	if !c.Asleep {
+		t.Fatalf("Expected a character standing still to fall asleep")
+	}
+
+	// A sleeping character is not moved, even if its floor is removed.
+	c.Segments = nil
+	at := c.Body.Center
+	stepCharacter(t, c, Input{}, 10)
+	if !c.Asleep || c.Body.Center != at || len(c.Contacts) != 0 {
+		t.Errorf("Expected a sleeping character to stay at %v, got %v, asleep=%t, %v",
+			at, c.Body.Center, c.Asleep, c.Contacts)
+	}
+	c.Wake()
+	stepCharacter(t, c, Input{}, 1)
+	if c.Asleep || c.Body.Center[1] >= at[1] {
+		t.Errorf("Expected a woken character to fall from %v, got %v", at, c.Body.Center)
+	}
+
+	// Input wakes a sleeping character.
+	for _, in := range []Input{{Move: 1}, {Move: -0.5}, {Jump: true}} {
+		c := standingCharacter(t, 100)
+		stepCharacter(t, c, in, 1)
+		if c.Asleep || c.Velocity.NearZero() {
+			t.Errorf("Expected %+v to wake a sleeping character, got asleep=%t, %v", in, c.Asleep, c.Velocity)
+		}
+	}
+
+	// A character standing on a gentle slope sleeps instead of
+	// creeping down it.
+	slope := []Segment{{{0, 0}, {200, 40}}}
+	c = NewCharacter(Ellipse{Center: Point{100, 45}, Radii: Vector{10, 20}}, slope)
+	stepCharacter(t, c, Input{}, 30)
+	at = c.Body.Center
+	stepCharacter(t, c, Input{}, 60)
+	if !c.Grounded || !c.Asleep || c.Body.Center != at {
+		t.Errorf("Expected a character to sleep on a gentle slope at %v, got %v, grounded=%t, asleep=%t",
+			at, c.Body.Center, c.Grounded, c.Asleep)
+	}
+	c = NewCharacter(Ellipse{Center: Point{100, 45}, Radii: Vector{10, 20}}, slope)
+	c.StopSpeed = 0
+	stepCharacter(t, c, Input{}, 30)
+	at = c.Body.Center
+	stepCharacter(t, c, Input{}, 60)
+	if c.Asleep || c.Body.Center[0] >= at[0] {
+		t.Errorf("Expected a character with no stop speed to creep down a slope from %v, got %v, asleep=%t",
+			at, c.Body.Center, c.Asleep)
+	}
+}
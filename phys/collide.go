@@ -5,104 +5,449 @@ package phys
 // Basically an implementation of: http://www.paulnettle.com/pub/FluidStudios/CollisionDetection/Fluid_Studios_Generic_Collision_Detection_for_Games_Using_Ellipsoids.pdf
 
 import (
+	"errors"
 	"math"
 
 	. "github.com/eaburns/quart/geom"
 )
 
-const (
-	// The factor of the height of a body that is considered to be the
-	// bottom.  This is used to determine if the body is on the ground
-	// or if it is in the air: if the bottom of the body collides, then the
-	// body is "on the ground."
-	//
-	// In effect, this determines how steep of a hill a body can climb.
-	// The steeper the hill, the higher up on the body that it will collide.
-	// So, a higher value for bottomFactor means the body can climb
-	// steeper hills.  For elliptical bodies, the steepness of climbable
-	// hills is also determined by the height of the ellipse.
-	bottomFactor = 0.05
+var (
+	// ErrMaxSlides is returned when a body slides more than the
+	// MaxSlides option times during a single move.
+	ErrMaxSlides = errors.New("phys: too many slides")
+
+	// ErrStuck is returned when a body collides but cannot slide along
+	// the surface that it hit.
+	ErrStuck = errors.New("phys: stuck")
 )
 
 // MoveEllipse moves an ellipse with a given velocity, handling collision with segments.
-// The second return value is true if the ellipse collided with a segment beneath it,
-// otherwise it is false.  This value can be used to decide if it is "on the ground."
-func MoveEllipse(e Ellipse, v Vector, segs []Segment) (Ellipse, bool) {
-	tr := Vector{}
-	for i, r := range e.Radii {
-		tr[i] = 1 / r
+// The second return value lists the contacts made with the segments, in order,
+// and OnGround can be used with it to decide if the ellipse is "on the ground."
+//
+// If the ellipse cannot finish the move, because it slid more than MaxSlides
+// times or it became stuck, then it is left at the last position that it reached
+// and a non-nil error is returned along with the contacts made so far.
+//
+// The options control the move; if they are nil then the defaults are used.
+func MoveEllipse(e Ellipse, v Vector, segs []Segment, opts *MoveOptions) (Ellipse, []Contact, error) {
+	o := opts.withDefaults()
+	return moveEllipse(e, v, o.filtered(segmentSlice(segs)), o)
+}
+
+// MoveCircle moves a circle with a given velocity, handling collision with segments.
+// The second return value lists the contacts made with the segments, in order,
+// and OnGround can be used with it to decide if the circle is "on the ground."
+// The error and options are as described for MoveEllipse.
+func MoveCircle(c Circle, v Vector, segs []Segment, opts *MoveOptions) (Circle, []Contact, error) {
+	o := opts.withDefaults()
+	return moveCircle(c, v, o.filtered(segmentSlice(segs)), o, o.skin)
+}
+
+// MoveCapsule moves a capsule with a given velocity, handling collision with segments.
+// The second return value lists the contacts made with the segments, in order,
+// and OnGround can be used with it to decide if the capsule is "on the ground."
+// The error and options are as described for MoveEllipse.
+func MoveCapsule(c Capsule, v Vector, segs []Segment, opts *MoveOptions) (Capsule, []Contact, error) {
+	o := opts.withDefaults()
+	return moveCapsule(c, v, o.filtered(segmentSlice(segs)), o)
+}
+
+// MovePolygon moves a polygon with a given velocity, handling collision with segments.
+// The second return value lists the contacts made with the segments, in order,
+// and OnGround can be used with it to decide if the polygon is "on the ground."
+// The error and options are as described for MoveEllipse.
+// The returned polygon is a translated copy; the polygon passed in is not modified.
+func MovePolygon(poly Polygon, v Vector, segs []Segment, opts *MoveOptions) (Polygon, []Contact, error) {
+	o := opts.withDefaults()
+	return movePolygon(poly, v, o.filtered(segmentSlice(segs)), o)
+}
+
+func moveEllipse(e Ellipse, v Vector, segs segmentSet, opts MoveOptions) (Ellipse, []Contact, error) {
+	tr, inv := unitCircleSpace(e)
+	c := Circle{Center: tr.ApplyPoint(e.Center), Radius: 1}
+	// The skin width is a distance in the world, so it is shorter
+	// in the unit circle space along the longer radius.
+	skin := func(n Vector) float64 {
+		return tr.ApplyVector(inv.ApplyNormal(n).ScaledBy(opts.SkinWidth)).Magnitude()
+	}
+	c2, contacts, err := moveCircle(c, tr.ApplyVector(v), transformedSet{segs, tr, inv}, opts, skin)
+	for i := range contacts {
+		contacts[i].Point = inv.ApplyPoint(contacts[i].Point)
+		contacts[i].Normal = inv.ApplyNormal(contacts[i].Normal)
+		contacts[i].Kind = opts.contactKind(contacts[i].Point, contacts[i].Normal)
 	}
+	return Ellipse{Center: inv.ApplyPoint(c2.Center), Radii: e.Radii}, contacts, err
+}
+
+// unitCircleSpace returns a transform into the space in which the ellipse
+// is a unit circle, and the inverse transform back out of it.
+func unitCircleSpace(e Ellipse) (Transform, Transform) {
+	tr := Scale(Vector{1 / e.Radii[0], 1 / e.Radii[1]})
+	return tr, Scale(e.Radii)
+}
 
-	c := Circle{Center: e.Center.Times(tr), Radius: 1}
-	v = v.Times(tr)
-	trSegs := make([]Segment, len(segs))
-	for i := range segs {
-		trSegs[i][0] = segs[i][0].Times(tr)
-		trSegs[i][1] = segs[i][1].Times(tr)
+func moveCircle(c Circle, v Vector, segs segmentSet, opts MoveOptions, skin skinWidth) (Circle, []Contact, error) {
+	if opts.Depenetrate {
+		c, _, _ = resolveCircle(c, segs)
 	}
-	c2, onGround := MoveCircle(c, v, trSegs)
-	return Ellipse{Center: c2.Center.Times(e.Radii), Radii: e.Radii}, onGround
+	b := &circleBody{c}
+	contacts, err := moveBody(b, v, segs, opts, skin)
+	return b.Circle, contacts, err
 }
 
-// MoveCircle moves a circle with a given velocity, handling collision with segments.
-// The second return value is true if the circle collided with a segment beneath it,
-// otherwise it is false.  This value can be used to decide if it is "on the ground."
-func MoveCircle(c Circle, v Vector, segs []Segment) (Circle, bool) {
-	onGround := false
-	for !v.NearZero() {
-		mv := moveCircle1(c, v, segs)
-		c.Center.Add(v.Unit().ScaledBy(mv.distance))
-		low := c.Center[1] - c.Radius*(1-bottomFactor*2)
-		hitGround := v[1] < 0 && mv.hit && mv.hitPoint[1] < low
-		onGround = onGround || hitGround
+func moveCapsule(c Capsule, v Vector, segs segmentSet, opts MoveOptions) (Capsule, []Contact, error) {
+	b := &capsuleBody{c}
+	contacts, err := moveBody(b, v, segs, opts, opts.skin)
+	return b.Capsule, contacts, err
+}
+
+func movePolygon(poly Polygon, v Vector, segs segmentSet, opts MoveOptions) (Polygon, []Contact, error) {
+	b := &polygonBody{append(Polygon(nil), poly...)}
+	contacts, err := moveBody(b, v, segs, opts, opts.skin)
+	return b.Polygon, contacts, err
+}
+
+// A segmentSet is a set of segments that a body can collide with.
+type segmentSet interface {
+	// near calls a function with each segment, and its index, that
+	// may intersect a rectangle.
+	near(r Rectangle, f func(int, Segment))
+
+	// props returns the properties of the segment with the given index.
+	props(i int) segmentProps
+}
+
+// A segmentSlice is a segmentSet without a spatial index.  Every
+// segment is near every rectangle.
+type segmentSlice []Segment
+
+func (segs segmentSlice) near(_ Rectangle, f func(int, Segment)) {
+	for i, s := range segs {
+		f(i, s)
+	}
+}
+
+func (segmentSlice) props(int) segmentProps {
+	return segmentProps{}
+}
+
+// A transformedSet is a segmentSet in the space given by a transform.
+// Tr transforms into the space, and inv transforms back out of it.
+type transformedSet struct {
+	segs    segmentSet
+	tr, inv Transform
+}
+
+func (t transformedSet) near(r Rectangle, f func(int, Segment)) {
+	t.segs.near(t.inv.ApplyRectangle(r), func(i int, s Segment) {
+		f(i, t.tr.ApplySegment(s))
+	})
+}
+
+func (t transformedSet) props(i int) segmentProps {
+	p := t.segs.props(i)
+	if p.curve.curve != nil {
+		p.curve.curve = transformedCurve{p.curve.curve, t.tr}
+	}
+	return p
+}
+
+// nearCircles transforms circles into the space.  It is only correct
+// if the transform is a uniform scale, so that circles remain circles.
+func (t transformedSet) nearCircles(r Rectangle, f func(int, Circle)) {
+	cs, ok := t.segs.(circleSet)
+	if !ok {
+		return
+	}
+	cs.nearCircles(t.inv.ApplyRectangle(r), func(i int, c Circle) {
+		f(i, Circle{Center: t.tr.ApplyPoint(c.Center), Radius: c.Radius * math.Abs(t.tr[0][0])})
+	})
+}
+
+// A circleSet is a segmentSet that also contains circular obstacles.
+// A moving circle collides with the circles exactly; other bodies
+// ignore them.
+type circleSet interface {
+	segmentSet
+
+	// nearCircles calls a function with each circle, and its index,
+	// that may intersect a rectangle.  The indices share a space with
+	// those of the segments.
+	nearCircles(r Rectangle, f func(int, Circle))
+}
+
+// A body is a shape that can be moved through a set of segments.
+type body interface {
+	// hit returns information about the collision of the body moving
+	// along a velocity vector with a segment.  The return values are
+	// the distance along the velocity vector of the collision, the point
+	// on the segment that collided, the unit normal of the collision pointing
+	// from the point toward the body, and a boolean that is true if there was
+	// a collision and false if not.
+	hit(v Vector, s Segment) (float64, Point, Vector, bool)
+
+	// translate moves the body by a vector.
+	translate(v Vector)
+
+	// bounds returns the smallest rectangle containing the body.
+	bounds() Rectangle
+
+	// front returns true if the body is entirely on the front side of
+	// the line through a segment: the side toward which its normal points.
+	front(s Segment) bool
+}
+
+// A skinWidth returns the skin width of a body, in the space in which it moves,
+// along a unit normal in that space.
+type skinWidth func(n Vector) float64
+
+// moveBody moves a body with a given velocity, handling collision with segments.
+// The return value is the list of contacts made with the segments, and an error
+// if the body could not complete the move.
+func moveBody(b body, v Vector, segs segmentSet, opts MoveOptions, skin skinWidth) ([]Contact, error) {
+	var contacts []Contact
+	var prev Vector
+	var piece curvePiece
+	total, traveled := v.Magnitude(), 0.0
+	for slides := 0; !v.NearZero(); {
+		if slides > opts.MaxSlides {
+			return contacts, ErrMaxSlides
+		}
+		mv, err := moveBody1(b, v, segs, skin)
+		if err != nil {
+			return contacts, err
+		}
+		b.translate(v.Unit().ScaledBy(mv.distance))
+		traveled += mv.distance
+		if mv.hit {
+			contacts = append(contacts, Contact{
+				Segment:  mv.segment,
+				Point:    mv.hitPoint,
+				Normal:   mv.normal,
+				Time:     math.Max(0, math.Min(1, traveled/total)),
+				Kind:     opts.contactKind(mv.hitPoint, mv.normal),
+				Material: segs.props(mv.segment).material,
+			})
+		}
 		v = mv.newVelocity
+		if mv.hit {
+			v = crease(v, mv.face, prev)
+			prev = mv.face
+			// Sliding from one piece of a curve onto the next is
+			// a single slide along the curve.
+			p := segs.props(mv.segment).curve
+			if !p.follows(piece) {
+				slides++
+			}
+			piece = p
+		}
 	}
-	return c, onGround
+	return contacts, nil
+}
+
+// crease returns the velocity of a body after hitting a face, given
+// its velocity sliding along the face and the normal of the face hit
+// before it.  If the slide leads back into the previous face then the
+// body is in a crease between them, like the bottom of a V, along which
+// there is no direction to slide in two dimensions, so it stops.  Without
+// stopping, it would slide back and forth between the faces, traveling
+// less each time, until it ran out of slides.  Bounces away from the face
+// are unchanged.
+func crease(v, face, prev Vector) Vector {
+	if prev.NearZero() || v.NearZero() {
+		return v
+	}
+	u := v.Unit()
+	if u.Dot(face) > Threshold || u.Dot(prev) >= -Threshold {
+		return v
+	}
+	return Vector{}
 }
 
 type move struct {
 	distance    float64
 	newVelocity Vector
 	hit         bool
+	segment     int
 	hitPoint    Point
+	normal      Vector
+	face        Vector
 }
 
-// moveCircle1 moves a circle along a vector until the first collision with a Segment.
-func moveCircle1(c Circle, v Vector, segs []Segment) move {
-	hitPt := Point{}
-	dist := math.Inf(1)
-
-	for _, s := range segs {
-		if d, pt, hit := circleSegmentHit(c, v, s); hit && d < dist {
-			dist = d
-			hitPt = pt
-		}
-	}
-	if math.IsInf(dist, 1) {
+// moveBody1 moves a body along a vector until the first collision with a Segment.
+func moveBody1(b body, v Vector, segs segmentSet, skin skinWidth) (move, error) {
+	h, face, ok := firstHit(b, v, segs, skin(v.Unit()))
+	if !ok {
 		return move{
 			distance:    v.Magnitude(),
 			newVelocity: Vector{},
+		}, nil
+	}
+
+	// The normal is not a number if the body was already touching the
+	// point that it hit, and there is no way to know which way to slide.
+	if !(face.SquaredMagnitude() > 0) {
+		return move{}, ErrStuck
+	}
+	// The rest of the move slides along, or bounces off of, the
+	// surface according to its material.
+	rest := v.Unit().ScaledBy(v.Magnitude() - h.Distance)
+	rest = segs.props(h.Segment).material.respond(rest, face, Vector{})
+
+	return move{
+		distance:    h.Distance - skin(face),
+		newVelocity: rest,
+		hit:         true,
+		segment:     h.Segment,
+		hitPoint:    h.Point,
+		normal:      h.Normal,
+		face:        face,
+	}, nil
+}
+
+// firstHit returns the first collision of a body moving along a vector
+// with a set of segments.  Segments within the skin width of the body's path
+// are considered.  The second return value is the normal of the face that
+// was hit.  It differs from the normal of the hit for the segments of a curve,
+// whose hits have the normal of the curve; the body must still slide along
+// the face.  The third return value is false if there is no collision.
+func firstHit(b body, v Vector, segs segmentSet, skin float64) (Hit, Vector, bool) {
+	h := Hit{Segment: -1, Distance: math.Inf(1)}
+	var face Vector
+
+	// Segments are visited in an arbitrary order, so ties
+	// go to the lowest index to keep the result deterministic.
+	box := b.bounds()
+	box = box.Union(Rectangle{Min: box.Min.Plus(v), Size: box.Size}).Expand(skin)
+	closest := func(i int, d float64, pt Point, n, f Vector, hit bool) {
+		if hit && (d < h.Distance || d == h.Distance && i < h.Segment) {
+			h = Hit{Segment: i, Point: pt, Normal: n, Distance: d}
+			face = f
+		}
+	}
+	segs.near(box, func(i int, s Segment) {
+		if segs.props(i).oneWay && !blocks(b, v, s) {
+			return
+		}
+		d, pt, f, hit := b.hit(v, s)
+		n := f
+		if c := segs.props(i).curve; hit && c.curve != nil && f.SquaredMagnitude() > 0 {
+			n = c.normal(s, pt, f)
+		}
+		closest(i, d, pt, n, f, hit)
+	})
+	if c, ok := b.(*circleBody); ok {
+		if cs, ok := segs.(circleSet); ok {
+			cs.nearCircles(box, func(i int, o Circle) {
+				d, pt, n, hit := c.hitCircle(v, o)
+				closest(i, d, pt, n, n, hit)
+			})
 		}
 	}
+	return h, face, !math.IsInf(h.Distance, 1)
+}
 
-	vUnit := v.Unit()
-	c.Center.Add(vUnit.ScaledBy(dist))
-	slide := Plane{Origin: hitPt, Normal: hitPt.Minus(c.Center).Unit()}
+// blocks returns true if a one-way segment blocks a body moving
+// along a velocity vector.  It only blocks the body if the body is moving
+// against its normal and is entirely in front of it.
+func blocks(b body, v Vector, s Segment) bool {
+	return v.Dot(s.Normal()) < 0 && b.front(s)
+}
 
-	dest := hitPt.Plus(vUnit.ScaledBy(v.Magnitude() - dist))
-	r := Ray{Origin: dest, Direction: slide.Normal}
-	d, hit := r.PlaneIntersection(slide)
+// A circleBody is a body with the shape of a circle.
+type circleBody struct {
+	Circle
+}
+
+func (c *circleBody) hit(v Vector, s Segment) (float64, Point, Vector, bool) {
+	d, pt, hit := circleSegmentHit(c.Circle, v, s)
 	if !hit {
-		panic("Couldn't project to the sliding plane!")
+		return 0, Point{}, Vector{}, false
 	}
-	dest.Add(slide.Normal.Unit().ScaledBy(d))
+	center := c.Center.Plus(v.Unit().ScaledBy(d))
+	return d, pt, center.Minus(pt).Unit(), true
+}
 
-	return move{
-		distance:    dist - Threshold,
-		newVelocity: dest.Minus(hitPt),
-		hit:         true,
-		hitPoint:    hitPt,
+// hitCircle is like hit, but for a collision with a circle.
+func (c *circleBody) hitCircle(v Vector, o Circle) (float64, Point, Vector, bool) {
+	r := Ray{Origin: c.Center, Direction: v.Unit()}
+	d, hit := r.SphereIntersection(Sphere{Center: o.Center, Radius: o.Radius + c.Radius})
+	if !hit || d > v.Magnitude() {
+		return 0, Point{}, Vector{}, false
+	}
+	if d < 0 {
+		// The circles already overlap.  They only collide if
+		// the circle is moving toward the center of the other.
+		if v.Dot(o.Center.Minus(c.Center)) <= 0 {
+			return 0, Point{}, Vector{}, false
+		}
+		d = 0
+	}
+	n := c.Center.Plus(r.Direction.ScaledBy(d)).Minus(o.Center).Unit()
+	return d, o.Center.Plus(n.ScaledBy(o.Radius)), n, true
+}
+
+func (c *circleBody) translate(v Vector) {
+	c.Center.Add(v)
+}
+
+func (c *circleBody) bounds() Rectangle {
+	return c.Circle.Bounds()
+}
+
+func (c *circleBody) front(s Segment) bool {
+	return inFront(c.Center, c.Radius, s)
+}
+
+// A capsuleBody is a body with the shape of a capsule.
+type capsuleBody struct {
+	Capsule
+}
+
+func (c *capsuleBody) hit(v Vector, s Segment) (float64, Point, Vector, bool) {
+	// The first contact between two segments, one of which is
+	// surrounded by a radius, is either when the circle around one of
+	// the end points of the capsule hits the segment, or when one of
+	// the end points of the segment hits the capsule.
+	dist, hitPt, normal := math.Inf(1), Point{}, Vector{}
+	vUnit := v.Unit()
+	for _, p := range c.Segment {
+		cir := Circle{Center: p, Radius: c.Radius}
+		if d, pt, hit := circleSegmentHit(cir, v, s); hit && d < dist {
+			dist, hitPt = d, pt
+			normal = p.Plus(vUnit.ScaledBy(d)).Minus(pt).Unit()
+		}
+	}
+	// The end points of the segment only hit the front of the capsule,
+	// as the end circles only hit the front of the segment.
+	ends := s[:]
+	if !degenerate(s) && (s.Normal().Dot(vUnit) > 0 || !inFront(c.Segment[0], 0, s) && !inFront(c.Segment[1], 0, s)) {
+		ends = nil
+	}
+	for _, p := range ends {
+		r := Ray{Origin: p, Direction: vUnit.Inverse()}
+		if h, hit := r.CapsuleHit(c.Capsule); hit && !h.Inside && h.Distance <= v.Magnitude() && h.Distance < dist {
+			dist, hitPt, normal = h.Distance, p, h.Normal.Inverse()
+		}
 	}
+	if math.IsInf(dist, 1) {
+		return 0, Point{}, Vector{}, false
+	}
+	return dist, hitPt, normal, true
+}
+
+func (c *capsuleBody) translate(v Vector) {
+	c.Segment[0].Add(v)
+	c.Segment[1].Add(v)
+}
+
+func (c *capsuleBody) bounds() Rectangle {
+	return c.Capsule.Bounds()
+}
+
+func (c *capsuleBody) front(s Segment) bool {
+	return inFront(c.Segment[0], c.Radius, s) && inFront(c.Segment[1], c.Radius, s)
 }
 
 // circleSegmentHit returns information about the collision of a circle
@@ -147,3 +492,105 @@ func circlePlaneHit(c Circle, v Vector, p Plane) (Point, bool) {
 	}
 	return r.Origin.Plus(r.Direction.ScaledBy(d)), true
 }
+
+// A polygonBody is a body with the shape of a polygon.
+type polygonBody struct {
+	Polygon
+}
+
+func (poly *polygonBody) hit(v Vector, s Segment) (float64, Point, Vector, bool) {
+	// The first contact between a polygon and a segment is either when
+	// a vertex of the polygon hits the segment, or when an end point
+	// of the segment hits an edge of the polygon.  Motion parallel to an
+	// edge or segment cannot collide with it, but it may slide along it.
+	// Like the other bodies, polygons only collide with the front of a
+	// segment, so they pass through segments that they are behind or
+	// that they move away from.
+	dist, hitPt, normal := math.Inf(1), Point{}, Vector{}
+	vMag := v.Magnitude()
+	vUnit := v.Unit()
+	if !degenerate(s) && (s.Normal().Dot(vUnit) > 0 || poly.behind(s)) {
+		return 0, Point{}, Vector{}, false
+	}
+
+	if n := s.Normal(); !degenerate(s) && !parallel(vUnit, n) {
+		for _, p := range poly.Polygon {
+			r := Ray{Origin: p, Direction: vUnit}
+			if pt, d, _, hit := r.SegmentIntersection(s); hit && d <= vMag && d < dist {
+				dist, hitPt, normal = d, pt, n
+			}
+		}
+	}
+
+	back := vUnit.Inverse()
+	for i := range poly.Polygon {
+		e := poly.Edge(i)
+		n := e.Normal()
+		if degenerate(e) || parallel(vUnit, n) {
+			continue
+		}
+		if n.Dot(vUnit) > 0 {
+			n = n.Inverse()
+		}
+		for _, p := range s {
+			r := Ray{Origin: p, Direction: back}
+			if _, d, _, hit := r.SegmentIntersection(e); hit && d <= vMag && d < dist {
+				dist, hitPt, normal = d, p, n
+			}
+		}
+	}
+
+	if math.IsInf(dist, 1) {
+		return 0, Point{}, Vector{}, false
+	}
+	return dist, hitPt, normal, true
+}
+
+func (poly *polygonBody) translate(v Vector) {
+	for i := range poly.Polygon {
+		poly.Polygon[i].Add(v)
+	}
+}
+
+func (poly *polygonBody) bounds() Rectangle {
+	return poly.Polygon.Bounds()
+}
+
+// behind returns true if the polygon is entirely behind the line through
+// a segment.
+func (poly *polygonBody) behind(s Segment) bool {
+	for _, p := range poly.Polygon {
+		if inFront(p, 0, s) {
+			return false
+		}
+	}
+	return true
+}
+
+func (poly *polygonBody) front(s Segment) bool {
+	for _, p := range poly.Polygon {
+		if !inFront(p, 0, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// inFront returns true if a point is at least a given distance in front of
+// the line through a segment.  Points within Threshold of the distance
+// are considered to be in front, since a body resting on a segment is
+// left just short of it.
+func inFront(p Point, dist float64, s Segment) bool {
+	return p.Minus(s[0]).Dot(s.Normal()) >= dist-Threshold
+}
+
+// parallel returns true if motion in the direction of the unit vector v is
+// parallel to a surface with the unit normal n.
+func parallel(v, n Vector) bool {
+	return NearZero(v.Dot(n))
+}
+
+// degenerate returns true if the segment has no length.
+func degenerate(s Segment) bool {
+	return NearZero(s.Length())
+}
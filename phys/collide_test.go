@@ -0,0 +1,178 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+// box is a square room from 0,0 to 100,100 with its normals pointing in.
+var box = []Segment{
+	{{0, 0}, {100, 0}},
+	{{100, 0}, {100, 100}},
+	{{100, 100}, {0, 100}},
+	{{0, 100}, {0, 0}},
+}
+
+func TestMoveEllipseSkinWidth(t *testing.T) {
+	tests := []struct {
+		e    Ellipse
+		v    Vector
+		skin float64
+		// gap returns the distance from the ellipse to the wall that it hit.
+		gap func(Ellipse) float64
+	}{
+		{
+			Ellipse{Center: Point{50, 80}, Radii: Vector{25, 50}}, Vector{0, -100}, 0.5,
+			func(e Ellipse) float64 { return e.Center[1] - e.Radii[1] },
+		},
+		{
+			Ellipse{Center: Point{50, 80}, Radii: Vector{10, 5}}, Vector{0, -100}, 0.5,
+			func(e Ellipse) float64 { return e.Center[1] - e.Radii[1] },
+		},
+		{
+			Ellipse{Center: Point{50, 50}, Radii: Vector{25, 10}}, Vector{100, 0}, 0.25,
+			func(e Ellipse) float64 { return 100 - e.Center[0] - e.Radii[0] },
+		},
+		{
+			Ellipse{Center: Point{50, 50}, Radii: Vector{25, 10}}, Vector{0, 100}, 1,
+			func(e Ellipse) float64 { return 100 - e.Center[1] - e.Radii[1] },
+		},
+	}
+	for _, test := range tests {
+		e, _, err := MoveEllipse(test.e, test.v, box, &MoveOptions{SkinWidth: test.skin})
+		if err != nil {
+			t.Errorf("Moving %v by %v: unexpected error %v", test.e, test.v, err)
+			continue
+		}
+		if gap := test.gap(e); math.Abs(gap-test.skin) > 1e-6 {
+			t.Errorf("Moving %v by %v with skin width %g: expected a gap of %g, got %g",
+				test.e, test.v, test.skin, test.skin, gap)
+		}
+	}
+}
+
+func TestMoveFrontSideOnly(t *testing.T) {
+	// Each body starts above a floor and falls far enough to hit it.
+	type mover func(v Vector, segs []Segment) ([]Contact, error)
+	movers := map[string]mover{
+		"circle": func(v Vector, segs []Segment) ([]Contact, error) {
+			_, cs, err := MoveCircle(Circle{Center: Point{50, 20}, Radius: 10}, v, segs, nil)
+			return cs, err
+		},
+		"capsule": func(v Vector, segs []Segment) ([]Contact, error) {
+			_, cs, err := MoveCapsule(Capsule{Segment: Segment{{50, 20}, {50, 40}}, Radius: 10}, v, segs, nil)
+			return cs, err
+		},
+		"polygon": func(v Vector, segs []Segment) ([]Contact, error) {
+			sq := NewRectangle(Point{40, 10}, Point{60, 30}).Polygon()
+			_, cs, err := MovePolygon(sq, v, segs, nil)
+			return cs, err
+		},
+	}
+	tests := []struct {
+		floor   Segment
+		v       Vector
+		blocked bool
+	}{
+		{Segment{{0, 0}, {100, 0}}, Vector{0, -50}, true},
+		{Segment{{0, 0}, {100, 0}}, Vector{10, -50}, true},
+		// The floor faces down, so the bodies are behind it.
+		{Segment{{100, 0}, {0, 0}}, Vector{0, -50}, false},
+		{Segment{{100, 0}, {0, 0}}, Vector{10, -50}, false},
+		// A short floor facing down, which the bodies could hit
+		// with its end points.
+		{Segment{{50.3, 0}, {49.7, 0}}, Vector{0, -50}, false},
+		{Segment{{50.3, 0}, {49.7, 0}}, Vector{10, -50}, false},
+	}
+	for name, move := range movers {
+		for _, test := range tests {
+			cs, err := move(test.v, []Segment{test.floor})
+			if err != nil {
+				t.Errorf("Moving a %s by %v onto %v: unexpected error %v", name, test.v, test.floor, err)
+				continue
+			}
+			if blocked := len(cs) > 0; blocked != test.blocked {
+				t.Errorf("Expected a %s moving by %v onto %v to be blocked=%t, got %t",
+					name, test.v, test.floor, test.blocked, blocked)
+			}
+		}
+	}
+}
+
+func TestMoveIntoLedgeEnd(t *testing.T) {
+	// The end of a ledge, facing up, blocks bodies that straddle it
+	// and move along it.
+	ledge := []Segment{{{70, 25}, {100, 25}}}
+	v := Vector{30, 0}
+	sq := NewRectangle(Point{40, 10}, Point{60, 30}).Polygon()
+	if _, cs, err := MovePolygon(sq, v, ledge, nil); err != nil || len(cs) == 0 {
+		t.Errorf("Expected a polygon moving by %v to hit the end of %v, got %v, %v", v, ledge[0], cs, err)
+	}
+	c := Capsule{Segment: Segment{{50, 20}, {50, 40}}, Radius: 10}
+	if _, cs, err := MoveCapsule(c, v, ledge, nil); err != nil || len(cs) == 0 {
+		t.Errorf("Expected a capsule moving by %v to hit the end of %v, got %v, %v", v, ledge[0], cs, err)
+	}
+}
+
+// vee returns a V, w units wide on each side and 10 units tall,
+// with its bottom at the origin.
+func vee(w float64) []Segment {
+	return []Segment{
+		{{-w, 10}, {0, 0}},
+		{{0, 0}, {w, 10}},
+	}
+}
+
+// wedge is a narrow V.
+var wedge = vee(3)
+
+// bowl is a floor that curves up to the right along many short segments.
+func bowl() []Segment {
+	var segs []Segment
+	prev := Point{-100, 0}
+	for x := 0.0; x <= 100; x += 5 {
+		p := Point{x, x * x / 100}
+		segs = append(segs, Segment{prev, p})
+		prev = p
+	}
+	return segs
+}
+
+func TestMoveSlides(t *testing.T) {
+	tests := []struct {
+		c         Circle
+		v         Vector
+		segs      []Segment
+		maxSlides int
+		err       error
+	}{
+		// Resting in a wedge.
+		{Circle{Center: Point{0, 5}, Radius: 1}, Vector{0, -10}, wedge, 2, nil},
+		{Circle{Center: Point{0, 5}, Radius: 1}, Vector{0, -10}, wedge, 10, nil},
+		{Circle{Center: Point{0, 5}, Radius: 1}, Vector{0, -10}, wedge, 100, nil},
+		{Circle{Center: Point{0.5, 5}, Radius: 1}, Vector{-1, -10}, wedge, 2, nil},
+		// Sliding into a corner.
+		{Circle{Center: Point{50, 50}, Radius: 10}, Vector{100, -100}, box, 2, nil},
+		// Sliding up a curve takes a slide for each segment.
+		{Circle{Center: Point{-50, 11}, Radius: 10}, Vector{200, 0}, bowl(), 2, ErrMaxSlides},
+		{Circle{Center: Point{-50, 11}, Radius: 10}, Vector{200, 0}, bowl(), 100, nil},
+	}
+	for _, test := range tests {
+		opts := &MoveOptions{MaxSlides: test.maxSlides}
+		c, _, err := MoveCircle(test.c, test.v, test.segs, opts)
+		if err != test.err {
+			t.Errorf("Moving %v by %v with %d slides: expected error %v, got %v",
+				test.c, test.v, test.maxSlides, test.err, err)
+		}
+		for _, s := range test.segs {
+			if d := s.NearestPoint(c.Center).Distance(c.Center); d < c.Radius-Threshold {
+				t.Errorf("Moving %v by %v with %d slides: %v ends overlapping %v",
+					test.c, test.v, test.maxSlides, c, s)
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	. "github.com/eaburns/quart/geom"
+)
+
+// A Contact describes a collision between a moving body and a segment
+// or another body.
+type Contact struct {
+	// Segment is the index of the segment that was hit, or -1 if
+	// the contact is with another body or a rigid body in a World.  For a contact with
+	// a Platform, it is the index of the segment in the platform.
+	Segment int
+
+	// Body is the ID of the body that was hit, or zero if the
+	// contact is with a segment.
+	Body BodyID
+
+	// Platform is the ID of the platform that was hit, or zero if the
+	// contact is not with a platform.
+	Platform PlatformID
+
+	// Rigid is the ID of the rigid body that was hit, or zero if the
+	// contact is not with a rigid body.
+	Rigid RigidID
+
+	// Point is the point on the surface at which the body touched it.
+	Point Point
+
+	// Normal is the unit normal of the collision, pointing from the
+	// surface toward the body.
+	Normal Vector
+
+	// Time is the fraction of the distance of the move that was
+	// traveled before the contact, between 0 and 1.
+	Time float64
+
+	// Kind is the kind of surface that was hit.
+	Kind ContactKind
+
+	// Material is the material of the surface that was hit.
+	Material Material
+}
+
+// A ContactKind classifies the surface of a contact relative to the body.
+type ContactKind int
+
+const (
+	// Wall is a contact with the side of the body.
+	Wall ContactKind = iota
+
+	// Ground is a contact with the bottom of the body.
+	Ground
+
+	// Ceiling is a contact with the top of the body.
+	Ceiling
+)
+
+func (k ContactKind) String() string {
+	switch k {
+	case Wall:
+		return "wall"
+	case Ground:
+		return "ground"
+	case Ceiling:
+		return "ceiling"
+	}
+	return "unknown"
+}
+
+// OnGround returns true if any of the contacts is with the ground.
+func OnGround(cs []Contact) bool {
+	_, ok := GroundContact(cs)
+	return ok
+}
+
+// GroundContact returns the last contact with the ground, which is the
+// surface that a body is standing on after a move.  The second return
+// value is false if none of the contacts is with the ground.
+func GroundContact(cs []Contact) (Contact, bool) {
+	for i := len(cs) - 1; i >= 0; i-- {
+		if cs[i].Kind == Ground {
+			return cs[i], true
+		}
+	}
+	return Contact{}, false
+}
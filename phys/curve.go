@@ -0,0 +1,78 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	. "github.com/eaburns/quart/geom"
+)
+
+// AddCurve adds segments approximating a curve, such as an Arc or a Bezier,
+// to the grid, and returns their indices.  The segments are within a distance
+// tol of the curve.  Bodies collide with and slide along the segments like any
+// others, so a smaller tol gives more, shorter segments.  However, sliding from
+// one segment onto the next along the curve does not count against the
+// MaxSlides option, so a body can follow the whole curve in a single move,
+// and contacts and casts that hit the segments report the normal of the curve
+// rather than that of the segment, so contact kinds, and velocity responses
+// such as bounces, change smoothly along the curve.  The segments have the
+// default properties; they can be changed using their indices.
+func (g *Grid) AddCurve(c Curve, tol float64) []int {
+	g.curves++
+	ts := c.Subdivide(tol)
+	var is []int
+	for i := 1; i < len(ts); i++ {
+		s := Segment{c.Point(ts[i-1]), c.Point(ts[i])}
+		if degenerate(s) {
+			continue
+		}
+		j := g.add(s)
+		g.prop(j).curve = curvePiece{c, g.curves, ts[i-1], ts[i]}
+		is = append(is, j)
+	}
+	return is
+}
+
+// A curvePiece is the part of a curve, between two parameters, that a
+// segment approximates.
+type curvePiece struct {
+	// Curve is nil if the segment is not part of a curve.
+	curve normaler
+
+	// ID distinguishes the curves added to a grid.
+	id     int
+	t0, t1 float64
+}
+
+// follows returns true if the piece is next to another piece of the same
+// curve, on either side.
+func (c curvePiece) follows(o curvePiece) bool {
+	return c.curve != nil && o.curve != nil && c.id == o.id && (c.t0 == o.t1 || c.t1 == o.t0)
+}
+
+// A normaler is a curve with normals.
+type normaler interface {
+	Normal(t float64) Vector
+}
+
+// normal returns the normal of a collision at a point on the segment of
+// a curve piece, given the normal of the collision with the segment itself.
+// The normal is the normal of the curve, facing the same side as n.
+func (c curvePiece) normal(s Segment, p Point, n Vector) Vector {
+	d := s[1].Minus(s[0])
+	u := Clamp(p.Minus(s[0]).Dot(d)/d.SquaredMagnitude(), 0, 1)
+	cn := c.curve.Normal(c.t0 + u*(c.t1-c.t0))
+	if cn.Dot(n) < 0 {
+		return cn.Inverse()
+	}
+	return cn
+}
+
+// A transformedCurve is a curve in the space given by a transform.
+type transformedCurve struct {
+	curve normaler
+	tr    Transform
+}
+
+func (t transformedCurve) Normal(x float64) Vector {
+	return t.tr.ApplyNormal(t.curve.Normal(x))
+}
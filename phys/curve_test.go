@@ -0,0 +1,72 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+// pipe is a quarter-pipe that curves up from the origin to a wall at x=100.
+var pipe = Arc{Center: Point{0, 100}, Radius: 100, Start: -math.Pi / 2, End: 0}
+
+func TestMoveAlongCurve(t *testing.T) {
+	v := Vector{150, 0}
+	for _, tol := range []float64{0.1, 0.01, 0.001} {
+		g := NewGrid(nil, 20)
+		is := g.AddCurve(pipe, tol)
+		if len(is) <= DefaultMaxSlides {
+			t.Fatalf("Expected the pipe flattened at %g to have more than %d segments, got %d",
+				tol, DefaultMaxSlides, len(is))
+		}
+		c, cs, err := g.MoveCircle(Circle{Center: Point{-50, 10.01}, Radius: 10}, v, nil)
+		if err != nil || len(cs) <= DefaultMaxSlides {
+			t.Errorf("Expected moving by %v to slide along the pipe flattened at %g, got %d contacts, %v",
+				v, tol, len(cs), err)
+			continue
+		}
+		// The circle stays against the pipe, and ends up further
+		// along it than where it first hit.
+		if d := c.Center.Distance(pipe.Center); d < 90-tol || d > 90 {
+			t.Errorf("Expected moving by %v to stay against the pipe flattened at %g, got %v", v, tol, c)
+		}
+		if c.Center[1] <= cs[0].Point[1]+10 {
+			t.Errorf("Expected moving by %v to slide up the pipe flattened at %g, got %v", v, tol, c)
+		}
+		for _, ct := range cs {
+			n := pipe.Center.Minus(ct.Point).Unit()
+			if ct.Normal.Minus(n).Magnitude() > 0.01 {
+				t.Errorf("Expected a contact at %v with the pipe to have the normal of the curve %v, got %v",
+					ct.Point, n, ct.Normal)
+			}
+		}
+	}
+}
+
+func TestMoveEllipseAlongCurve(t *testing.T) {
+	g := NewGrid(nil, 20)
+	g.AddCurve(pipe, 0.01)
+	e := Ellipse{Center: Point{-50, 20.01}, Radii: Vector{10, 20}}
+	e, cs, err := g.MoveEllipse(e, Vector{150, 0}, nil)
+	if err != nil || len(cs) <= DefaultMaxSlides {
+		t.Fatalf("Expected an ellipse to slide along the pipe, got %d contacts, %v", len(cs), err)
+	}
+	if e.Center[1] <= 20.01 || e.Center[0] >= 100-10 {
+		t.Errorf("Expected an ellipse to slide up the pipe, got %v", e)
+	}
+}
+
+func TestMoveAcrossCurves(t *testing.T) {
+	// Slides onto another curve are counted, even if it continues
+	// on from the end of the first.
+	g := NewGrid(nil, 20)
+	g.AddCurve(Arc{Center: pipe.Center, Radius: pipe.Radius, Start: -math.Pi / 2, End: -math.Pi / 4}, 0.1)
+	g.AddCurve(Arc{Center: pipe.Center, Radius: pipe.Radius, Start: -math.Pi / 4, End: 0}, 0.1)
+	opts := MoveOptions{MaxSlides: 1}
+	_, cs, err := g.MoveCircle(Circle{Center: Point{-50, 10.01}, Radius: 10}, Vector{150, 0}, &opts)
+	if err != ErrMaxSlides {
+		t.Errorf("Expected sliding from one curve onto another to run out of slides, got %d contacts, %v", len(cs), err)
+	}
+}
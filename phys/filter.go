@@ -0,0 +1,70 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	. "github.com/eaburns/quart/geom"
+)
+
+// DefaultFilter is the filter used in place of the zero Filter.
+var DefaultFilter = Filter{Category: 1, Mask: ^uint32(0)}
+
+// A Filter determines which segments and bodies collide with each other.
+// Two things collide if each one's category has a bit in common with the
+// mask of the other.  For example, a wall that only blocks enemies has the
+// enemy bit in its mask, and a ghost has a mask that matches nothing.
+//
+// The zero Filter is treated as DefaultFilter, which puts a segment or
+// body in category 1 and collides it with every category.  A Filter with
+// a non-zero Category is used as is, even if its Mask is zero.
+type Filter struct {
+	// Category is the set of categories that this belongs to.
+	Category uint32
+
+	// Mask is the set of categories that this collides with.
+	Mask uint32
+}
+
+// Collides returns true if things with the two filters collide.
+func (f Filter) Collides(g Filter) bool {
+	f, g = f.canon(), g.canon()
+	return f.Category&g.Mask != 0 && g.Category&f.Mask != 0
+}
+
+// canon returns the filter with the zero value replaced by the default.
+func (f Filter) canon() Filter {
+	if f == (Filter{}) {
+		return DefaultFilter
+	}
+	return f
+}
+
+// A filteredSet is a segmentSet without the segments that do not
+// collide with a filter, and without the ignored segments.
+type filteredSet struct {
+	segs   segmentSet
+	f      Filter
+	ignore []int
+}
+
+func (fs filteredSet) near(r Rectangle, f func(int, Segment)) {
+	fs.segs.near(r, func(i int, s Segment) {
+		if fs.f.Collides(fs.segs.props(i).filter) && !fs.ignored(i) {
+			f(i, s)
+		}
+	})
+}
+
+func (fs filteredSet) props(i int) segmentProps {
+	return fs.segs.props(i)
+}
+
+// ignored returns true if the segment with the given index is ignored.
+func (fs filteredSet) ignored(i int) bool {
+	for _, j := range fs.ignore {
+		if i == j {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,220 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"sort"
+	"testing"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+var (
+	// Players and enemies collide with everything, enemy walls only
+	// collide with enemies, and ghosts collide with nothing.
+	player    = Filter{Category: 1, Mask: ^uint32(0)}
+	enemy     = Filter{Category: 4, Mask: ^uint32(0)}
+	enemyWall = Filter{Category: 2, Mask: 4}
+	ghost     = Filter{Category: 8, Mask: 0}
+)
+
+func TestFilterCollides(t *testing.T) {
+	tests := []struct {
+		f, g     Filter
+		collides bool
+	}{
+		{Filter{}, Filter{}, true},
+		{Filter{}, DefaultFilter, true},
+		{Filter{}, player, true},
+		{Filter{}, enemyWall, false},
+		{player, enemy, true},
+		{player, enemyWall, false},
+		{enemy, enemyWall, true},
+		{enemyWall, enemyWall, false},
+		{ghost, Filter{}, false},
+		{ghost, ghost, false},
+		// A zero mask is used as is if the category is not zero.
+		{Filter{Category: 1}, Filter{}, false},
+		{Filter{Category: 1}, Filter{Category: 1}, false},
+	}
+	for _, test := range tests {
+		if c := test.f.Collides(test.g); c != test.collides {
+			t.Errorf("Expected %+v to collide with %+v=%t, got %t", test.f, test.g, test.collides, c)
+		}
+		if c := test.g.Collides(test.f); c != test.collides {
+			t.Errorf("Expected %+v to collide with %+v=%t, got %t", test.g, test.f, test.collides, c)
+		}
+	}
+}
+
+func TestFilteredSetNear(t *testing.T) {
+	g := NewGrid([]Segment{
+		{{0, 0}, {100, 0}},
+		{{0, 10}, {100, 10}},
+		{{0, 20}, {100, 20}},
+	}, 20)
+	g.SetFilter(1, enemyWall)
+	g.SetFilter(2, ghost)
+	tests := []struct {
+		f      Filter
+		ignore []int
+		near   []int
+	}{
+		{Filter{}, nil, []int{0}},
+		{player, nil, []int{0}},
+		{enemy, nil, []int{0, 1}},
+		{ghost, nil, []int{}},
+		{enemy, []int{1}, []int{0}},
+		{enemy, []int{0, 1}, []int{}},
+	}
+	for _, test := range tests {
+		near := []int{}
+		filteredSet{g, test.f, test.ignore}.near(g.bounds, func(i int, _ Segment) {
+			near = append(near, i)
+		})
+		sort.Ints(near)
+		if !equalInts(near, test.near) {
+			t.Errorf("Expected the segments near for %+v, ignoring %v, to be %v, got %v",
+				test.f, test.ignore, test.near, near)
+		}
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMoveFilter(t *testing.T) {
+	// A wall, facing left, that only blocks enemies.
+	g := NewGrid([]Segment{{{60, 0}, {60, 100}}}, 20)
+	g.SetFilter(0, enemyWall)
+	tests := []struct {
+		f       Filter
+		blocked bool
+	}{
+		{Filter{}, false},
+		{player, false},
+		{enemy, true},
+	}
+	for _, test := range tests {
+		opts := MoveOptions{Filter: test.f}
+		c, cs, err := g.MoveCircle(Circle{Center: Point{40, 50}, Radius: 5}, Vector{40, 0}, &opts)
+		if err != nil || (len(cs) > 0) != test.blocked || (c.Center[0] < 55) != test.blocked {
+			t.Errorf("Expected a circle with filter %+v to be blocked=%t, got %v, %v, %v", test.f, test.blocked, c, cs, err)
+		}
+	}
+}
+
+func TestWorldFilter(t *testing.T) {
+	// Each kind of obstacle, with the enemy wall filter, is between x=40
+	// and x=60 of a world without gravity.
+	wall := []Segment{{{40, 0}, {40, 100}}}
+	obstacles := []struct {
+		name string
+		add  func(w *World)
+	}{
+		{"segment", func(w *World) {
+			w.Grid = NewGrid(wall, 20)
+			w.Grid.SetFilter(0, enemyWall)
+		}},
+		{"platform", func(w *World) {
+			p := NewPlatform(wall)
+			p.Filter = enemyWall
+			w.AddPlatform(p)
+		}},
+		{"rigid body", func(w *World) {
+			r := NewRigidBox(NewRectangle(Point{40, 40}, Point{60, 60}), 0)
+			r.Filter = enemyWall
+			w.AddRigid(r)
+		}},
+		{"body", func(w *World) {
+			b := &Body{Ellipse: Ellipse{Center: Point{50, 50}, Radii: Vector{10, 10}}}
+			b.Options.Filter = enemyWall
+			w.Add(b)
+		}},
+	}
+	tests := []struct {
+		f       Filter
+		blocked bool
+	}{
+		{Filter{}, false},
+		{player, false},
+		{enemy, true},
+	}
+	for _, o := range obstacles {
+		for _, test := range tests {
+			w := NewWorld(nil, dt)
+			o.add(w)
+			b := &Body{Ellipse: Ellipse{Center: Point{25, 50}, Radii: Vector{5, 5}}, Velocity: Vector{10 / dt, 0}}
+			b.Options.Filter = test.f
+			w.Add(b)
+			stepWorld(w, 5)
+			if blocked := b.Ellipse.Center[0] < 40; blocked != test.blocked {
+				t.Errorf("Expected a body with filter %+v to be blocked=%t by a %s, got %v",
+					test.f, test.blocked, o.name, b.Ellipse)
+			}
+		}
+	}
+}
+
+func TestRigidFilter(t *testing.T) {
+	// A ledge that only blocks enemies.
+	g := NewGrid(ledgeSegs, 20)
+	g.SetFilter(1, enemyWall)
+	tests := []struct {
+		f       Filter
+		blocked bool
+	}{
+		{Filter{}, false},
+		{player, false},
+		{enemy, true},
+	}
+	for _, test := range tests {
+		w := NewWorld(g, dt)
+		w.Gravity = Vector{0, -500}
+		r := NewRigidCircle(Circle{Center: Point{100, 70}, Radius: 5}, 1)
+		r.Filter = test.f
+		w.AddRigid(r)
+		stepWorld(w, 60)
+		if blocked := r.Position[1] > 50; blocked != test.blocked {
+			t.Errorf("Expected a rigid body with filter %+v to be blocked=%t by the ledge, got %v",
+				test.f, test.blocked, r.Position)
+		}
+	}
+}
+
+func TestTriggerFilter(t *testing.T) {
+	tests := []struct {
+		f       Filter
+		entered bool
+	}{
+		{Filter{}, false},
+		{player, false},
+		{enemy, true},
+		{ghost, false},
+	}
+	for _, test := range tests {
+		w := NewWorld(nil, dt)
+		entered := false
+		w.AddTrigger(&Trigger{
+			Region: NewRectangle(Point{0, 0}, Point{100, 100}),
+			Filter: enemyWall,
+			Enter:  func(*Trigger, *Body) { entered = true },
+		})
+		b := &Body{Ellipse: Ellipse{Center: Point{50, 50}, Radii: Vector{5, 5}}}
+		b.Options.Filter = test.f
+		w.Add(b)
+		w.Step()
+		if entered != test.entered {
+			t.Errorf("Expected a body with filter %+v to set off the trigger=%t, got %t", test.f, test.entered, entered)
+		}
+	}
+}
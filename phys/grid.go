@@ -0,0 +1,256 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"math"
+	"sort"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+// A Grid is a spatial index of a set of segments.  Space is divided
+// into square cells, and each cell records the segments that pass through it,
+// so moving a body only needs to consider the segments in the cells that the
+// body sweeps through.
+//
+// A Grid is not safe for concurrent use.
+type Grid struct {
+	segs  []Segment
+	size  float64
+	cells map[cell][]int
+
+	// Copied is true if segs has been copied from the slice passed
+	// to NewGrid, so that segments can be appended to it.
+	copied bool
+
+	// Bounds is the smallest rectangle containing all of the segments.
+	bounds Rectangle
+
+	// SegProps are the properties of the segments.  It is nil
+	// if no property has been set.
+	segProps []segmentProps
+
+	// Curves is the number of curves added by AddCurve.
+	curves int
+
+	// Marks and stamp are used to visit each segment only once per query.
+	// A segment has been visited by the current query if its mark is equal
+	// to the stamp.
+	marks []uint32
+	stamp uint32
+}
+
+// SegmentProps are the properties of a segment.
+type segmentProps struct {
+	filter   Filter
+	oneWay   bool
+	material Material
+	curve    curvePiece
+}
+
+// A cell is the coordinate of a grid cell.
+type cell [2]int
+
+// NewGrid returns a new grid indexing a set of segments, with cells that
+// are a given size along each side.  A good cell size is around the size
+// of the bodies that will be moved through the grid.
+func NewGrid(segs []Segment, cellSize float64) *Grid {
+	if cellSize <= 0 {
+		panic("Grid cell size must be positive")
+	}
+	g := &Grid{
+		segs:  segs,
+		size:  cellSize,
+		cells: make(map[cell][]int),
+		marks: make([]uint32, len(segs)),
+	}
+	for i, s := range segs {
+		g.insert(i, s)
+	}
+	return g
+}
+
+// add adds a segment to the grid and returns its index.
+func (g *Grid) add(s Segment) int {
+	// The segments may share an array with the slice passed to
+	// NewGrid, so they are copied before the first append.
+	if !g.copied {
+		g.segs = append([]Segment(nil), g.segs...)
+		g.copied = true
+	}
+	i := len(g.segs)
+	g.segs = append(g.segs, s)
+	g.marks = append(g.marks, 0)
+	if g.segProps != nil {
+		g.segProps = append(g.segProps, segmentProps{})
+	}
+	g.insert(i, s)
+	return i
+}
+
+// insert adds the segment with the given index to the cells that it
+// passes through.
+func (g *Grid) insert(i int, s Segment) {
+	if i == 0 {
+		g.bounds = s.Bounds()
+	} else {
+		g.bounds = g.bounds.Union(s.Bounds())
+	}
+	lo, hi := g.cellRange(s.Bounds())
+	for x := lo[0]; x <= hi[0]; x++ {
+		for y := lo[1]; y <= hi[1]; y++ {
+			c := cell{x, y}
+			if segmentTouches(s, g.cellRect(c)) {
+				g.cells[c] = append(g.cells[c], i)
+			}
+		}
+	}
+}
+
+// Segments returns the segments indexed by the grid.
+func (g *Grid) Segments() []Segment {
+	return g.segs
+}
+
+// Query returns the indices of the segments that may intersect a rectangle,
+// in increasing order.  Segments that pass through a cell touched by the
+// rectangle are returned even if they do not intersect the rectangle itself.
+func (g *Grid) Query(r Rectangle) []int {
+	var is []int
+	g.near(r, func(i int, _ Segment) { is = append(is, i) })
+	sort.Ints(is)
+	return is
+}
+
+// MoveEllipse is like the MoveEllipse function, but it collides with the segments of the grid.
+func (g *Grid) MoveEllipse(e Ellipse, v Vector, opts *MoveOptions) (Ellipse, []Contact, error) {
+	o := opts.withDefaults()
+	return moveEllipse(e, v, o.filtered(g), o)
+}
+
+// MoveCircle is like the MoveCircle function, but it collides with the segments of the grid.
+func (g *Grid) MoveCircle(c Circle, v Vector, opts *MoveOptions) (Circle, []Contact, error) {
+	o := opts.withDefaults()
+	return moveCircle(c, v, o.filtered(g), o, o.skin)
+}
+
+// MoveCapsule is like the MoveCapsule function, but it collides with the segments of the grid.
+func (g *Grid) MoveCapsule(c Capsule, v Vector, opts *MoveOptions) (Capsule, []Contact, error) {
+	o := opts.withDefaults()
+	return moveCapsule(c, v, o.filtered(g), o)
+}
+
+// MovePolygon is like the MovePolygon function, but it collides with the segments of the grid.
+func (g *Grid) MovePolygon(poly Polygon, v Vector, opts *MoveOptions) (Polygon, []Contact, error) {
+	o := opts.withDefaults()
+	return movePolygon(poly, v, o.filtered(g), o)
+}
+
+// SetFilter sets the filter of the segment with the given index.
+func (g *Grid) SetFilter(i int, f Filter) {
+	g.prop(i).filter = f
+}
+
+// Filter returns the filter of the segment with the given index.
+func (g *Grid) Filter(i int) Filter {
+	return g.props(i).filter
+}
+
+// SetOneWay sets whether the segment with the given index is one-way.
+// A one-way segment, such as a platform that can be jumped up through,
+// only blocks bodies that are entirely in front of it and that are moving
+// against its normal.
+func (g *Grid) SetOneWay(i int, oneWay bool) {
+	g.prop(i).oneWay = oneWay
+}
+
+// OneWay returns true if the segment with the given index is one-way.
+func (g *Grid) OneWay(i int) bool {
+	return g.props(i).oneWay
+}
+
+// SetMaterial sets the material of the segment with the given index.
+func (g *Grid) SetMaterial(i int, m Material) {
+	g.prop(i).material = m
+}
+
+// Material returns the material of the segment with the given index.
+func (g *Grid) Material(i int) Material {
+	return g.props(i).material
+}
+
+func (g *Grid) props(i int) segmentProps {
+	if g.segProps == nil {
+		return segmentProps{}
+	}
+	return g.segProps[i]
+}
+
+// prop returns a pointer to the properties of a segment.
+func (g *Grid) prop(i int) *segmentProps {
+	if g.segProps == nil {
+		g.segProps = make([]segmentProps, len(g.segs))
+	}
+	return &g.segProps[i]
+}
+
+func (g *Grid) near(r Rectangle, f func(int, Segment)) {
+	g.stamp++
+	if g.stamp == 0 {
+		// The stamp wrapped around, so old marks may look current.
+		for i := range g.marks {
+			g.marks[i] = 0
+		}
+		g.stamp = 1
+	}
+	// Only the cells covered by the segments can hold any of them, so
+	// the query is clipped to their bounds, however large it is.
+	r, ok := r.Canon().Intersection(g.bounds)
+	if !ok || len(g.segs) == 0 {
+		return
+	}
+	lo, hi := g.cellRange(r)
+	for x := lo[0]; x <= hi[0]; x++ {
+		for y := lo[1]; y <= hi[1]; y++ {
+			for _, i := range g.cells[cell{x, y}] {
+				if g.marks[i] == g.stamp {
+					continue
+				}
+				g.marks[i] = g.stamp
+				f(i, g.segs[i])
+			}
+		}
+	}
+}
+
+// cellRange returns the lowest and highest cells touched by a rectangle.
+func (g *Grid) cellRange(r Rectangle) (cell, cell) {
+	return g.cellOf(r.Min), g.cellOf(r.Max())
+}
+
+// cellOf returns the cell containing a point.
+func (g *Grid) cellOf(p Point) cell {
+	return cell{int(math.Floor(p[0] / g.size)), int(math.Floor(p[1] / g.size))}
+}
+
+// cellRect returns the rectangle covered by a cell.
+func (g *Grid) cellRect(c cell) Rectangle {
+	return Rectangle{
+		Min:  Point{float64(c[0]) * g.size, float64(c[1]) * g.size},
+		Size: Vector{g.size, g.size},
+	}
+}
+
+// segmentTouches returns true if a segment intersects a rectangle.
+func segmentTouches(s Segment, r Rectangle) bool {
+	if r.Contains(s[0]) || r.Contains(s[1]) {
+		return true
+	}
+	for _, e := range r.Edges() {
+		if _, _, _, hit := s.Intersect(e); hit {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+// gridSegs are the segments of the grid tests: a floor, a long diagonal
+// crossing many cells, and a short segment within a single cell.
+var gridSegs = []Segment{
+	{{0, 0}, {100, 0}},
+	{{0, 0}, {100, 100}},
+	{{52, 12}, {54, 14}},
+}
+
+func TestGridQuery(t *testing.T) {
+	tests := []struct {
+		r  Rectangle
+		is []int
+	}{
+		{NewRectangle(Point{1, 1}, Point{9, 9}), []int{0, 1}},
+		{NewRectangle(Point{51, 11}, Point{55, 15}), []int{2}},
+		{NewRectangle(Point{45, 5}, Point{55, 15}), []int{0, 2}},
+		{NewRectangle(Point{85, 15}, Point{95, 25}), nil},
+		{NewRectangle(Point{200, 200}, Point{300, 300}), nil},
+		{NewRectangle(Point{-1e9, -1e9}, Point{1e9, 1e9}), []int{0, 1, 2}},
+		// Non-canonical rectangles are canonicalized.
+		{Rectangle{Min: Point{55, 15}, Size: Vector{-10, -10}}, []int{0, 2}},
+	}
+	g := NewGrid(gridSegs, 10)
+	for _, test := range tests {
+		if is := g.Query(test.r); !reflect.DeepEqual(is, test.is) {
+			t.Errorf("Expected query of %v to return %v, got %v", test.r, test.is, is)
+		}
+	}
+}
+
+func TestGridNearVisitsOnce(t *testing.T) {
+	tests := []Rectangle{
+		NewRectangle(Point{0, 0}, Point{100, 100}),
+		NewRectangle(Point{-50, -50}, Point{150, 150}),
+		NewRectangle(Point{40, -10}, Point{60, 60}),
+	}
+	g := NewGrid(gridSegs, 10)
+	for _, r := range tests {
+		visits := make(map[int]int)
+		g.near(r, func(i int, s Segment) {
+			visits[i]++
+			if s != gridSegs[i] {
+				t.Errorf("Expected segment %d to be %v, got %v", i, gridSegs[i], s)
+			}
+		})
+		for i, n := range visits {
+			if n != 1 {
+				t.Errorf("Expected segment %d to be visited once near %v, got %d", i, r, n)
+			}
+		}
+	}
+}
+
+func TestGridAdd(t *testing.T) {
+	// The slice passed to NewGrid has room for the added segments,
+	// but they must not be written into it.
+	segs := make([]Segment, 1, len(gridSegs))
+	segs[0] = gridSegs[0]
+	g := NewGrid(segs, 10)
+	for _, s := range gridSegs[1:] {
+		g.add(s)
+	}
+	if s := segs[:2][1]; s != (Segment{}) {
+		t.Errorf("Expected the slice passed to NewGrid to be unchanged, got %v", s)
+	}
+	if !reflect.DeepEqual(g.Segments(), gridSegs) {
+		t.Errorf("Expected segments %v, got %v", gridSegs, g.Segments())
+	}
+	if is := g.Query(NewRectangle(Point{51, 11}, Point{55, 15})); !reflect.DeepEqual(is, []int{2}) {
+		t.Errorf("Expected the added segment to be found, got %v", is)
+	}
+}
+
+func BenchmarkGridRaycastFar(b *testing.B) {
+	g := NewGrid(box, 10)
+	r := Ray{Origin: Point{50, 50}, Direction: Vector{1, 1}}
+	for i := 0; i < b.N; i++ {
+		g.Raycast(r, 1e6)
+	}
+}
+
+func BenchmarkGridAdd(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		g := NewGrid(box, 10)
+		for j := 0; j < 1000; j++ {
+			x := float64(j % 100)
+			g.add(Segment{{x, 50}, {x + 1, 51}})
+		}
+	}
+}
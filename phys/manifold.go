@@ -0,0 +1,205 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"math"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+// A convex is a convex shape: a polygon, with its vertices in
+// counter-clockwise order, and a radius around it.  A circle is a single
+// point with a radius, and a segment is a polygon with two points.  Only
+// circles have a radius.
+type convex struct {
+	pts    []Point
+	radius float64
+}
+
+// A manifold is the contact between two overlapping convex shapes.
+type manifold struct {
+	// Normal is the unit normal pointing from the first shape
+	// toward the second.
+	normal Vector
+
+	// Points are the points of contact, and depths are the
+	// distances that the shapes overlap at each point.
+	points []Point
+	depths []float64
+}
+
+// collideConvex returns the contact between two convex shapes.  The
+// second return value is false if they do not overlap.
+func collideConvex(a, b convex) (manifold, bool) {
+	switch {
+	case len(a.pts) == 1 && len(b.pts) == 1:
+		return collideCircles(a.pts[0], a.radius, b.pts[0], b.radius)
+	case len(b.pts) == 1:
+		return collidePolygonCircle(a.pts, b.pts[0], b.radius)
+	case len(a.pts) == 1:
+		m, ok := collidePolygonCircle(b.pts, a.pts[0], a.radius)
+		m.normal = m.normal.Inverse()
+		return m, ok
+	}
+	return collidePolygons(a.pts, b.pts)
+}
+
+func collideCircles(a Point, ra float64, b Point, rb float64) (manifold, bool) {
+	d := b.Minus(a)
+	dist := d.Magnitude()
+	if dist >= ra+rb {
+		return manifold{}, false
+	}
+	n := Vector{0, 1}
+	if dist > 0 {
+		n = d.ScaledBy(1 / dist)
+	}
+	depth := ra + rb - dist
+	return manifold{
+		normal: n,
+		points: []Point{a.Plus(n.ScaledBy(ra - depth/2))},
+		depths: []float64{depth},
+	}, true
+}
+
+// collidePolygonCircle returns the contact between a polygon, or a segment,
+// and a circle.
+func collidePolygonCircle(poly []Point, c Point, r float64) (manifold, bool) {
+	if len(poly) > 2 {
+		sep, e := math.Inf(-1), 0
+		for i := range poly {
+			s := c.Minus(poly[i]).Dot(outward(poly, i))
+			if s > sep {
+				sep, e = s, i
+			}
+		}
+		if sep >= r {
+			return manifold{}, false
+		}
+		if sep <= 0 {
+			// The center is inside of the polygon, so push out
+			// of the nearest edge.
+			n := outward(poly, e)
+			return manifold{
+				normal: n,
+				points: []Point{c.Plus(n.ScaledBy(-sep))},
+				depths: []float64{r - sep},
+			}, true
+		}
+	}
+	q, best := Point{}, math.Inf(1)
+	for i := range poly {
+		p := edge(poly, i).NearestPoint(c)
+		if d := p.SquaredDistance(c); d < best {
+			q, best = p, d
+		}
+	}
+	dist := math.Sqrt(best)
+	if dist >= r {
+		return manifold{}, false
+	}
+	n := outward(poly, 0)
+	if dist > 0 {
+		n = c.Minus(q).ScaledBy(1 / dist)
+	}
+	return manifold{normal: n, points: []Point{q}, depths: []float64{r - dist}}, true
+}
+
+// collidePolygons returns the contact between two polygons, or segments,
+// using the separating axis test.  The contact is on the edge, called the
+// reference edge, along which the polygons overlap the least.  Its points
+// are the ends of the edge of the other polygon most opposed to it, called
+// the incident edge, clipped to the sides of the reference edge.
+func collidePolygons(a, b []Point) (manifold, bool) {
+	sepA, ea := maxSeparation(a, b)
+	if sepA >= 0 {
+		return manifold{}, false
+	}
+	sepB, eb := maxSeparation(b, a)
+	if sepB >= 0 {
+		return manifold{}, false
+	}
+	ref, inc, e, flip := a, b, ea, false
+	// Prefer the first polygon, unless the second is clearly
+	// better, so that the choice does not flicker between steps.
+	if sepB > sepA+0.1*rigidSlop {
+		ref, inc, e, flip = b, a, eb, true
+	}
+	n := outward(ref, e)
+
+	i, min := 0, math.Inf(1)
+	for j := range inc {
+		if d := outward(inc, j).Dot(n); d < min {
+			i, min = j, d
+		}
+	}
+	pts := edge(inc, i)
+
+	s := edge(ref, e)
+	t := s[1].Minus(s[0]).Unit()
+	var ok bool
+	if pts, ok = clip(pts, t.Inverse(), -t.Dot(Vector(s[0]))); !ok {
+		return manifold{}, false
+	}
+	if pts, ok = clip(pts, t, t.Dot(Vector(s[1]))); !ok {
+		return manifold{}, false
+	}
+
+	var m manifold
+	m.normal = n
+	if flip {
+		m.normal = n.Inverse()
+	}
+	for _, p := range pts {
+		if sep := p.Minus(s[0]).Dot(n); sep < 0 {
+			m.points = append(m.points, p)
+			m.depths = append(m.depths, -sep)
+		}
+	}
+	return m, len(m.points) > 0
+}
+
+// maxSeparation returns the greatest distance, along the outward normal
+// of an edge of polygon a, from the edge to the nearest vertex of b, and
+// the index of the edge.  The polygons overlap if the distance is negative.
+func maxSeparation(a, b []Point) (float64, int) {
+	best, e := math.Inf(-1), 0
+	for i := range a {
+		n := outward(a, i)
+		min := math.Inf(1)
+		for _, p := range b {
+			min = math.Min(min, p.Minus(a[i]).Dot(n))
+		}
+		if min > best {
+			best, e = min, i
+		}
+	}
+	return best, e
+}
+
+// clip returns the part of a segment for which p·n <= o.  The second
+// return value is false if no part of the segment remains.
+func clip(s Segment, n Vector, o float64) (Segment, bool) {
+	d0, d1 := Vector(s[0]).Dot(n)-o, Vector(s[1]).Dot(n)-o
+	switch {
+	case d0 > 0 && d1 > 0:
+		return s, false
+	case d0 > 0:
+		s[0] = s[0].Plus(s[1].Minus(s[0]).ScaledBy(d0 / (d0 - d1)))
+	case d1 > 0:
+		s[1] = s[1].Plus(s[0].Minus(s[1]).ScaledBy(d1 / (d1 - d0)))
+	}
+	return s, true
+}
+
+// edge returns the ith edge of a polygon.
+func edge(poly []Point, i int) Segment {
+	return Polygon(poly).Edge(i)
+}
+
+// outward returns the outward normal of the ith edge of a
+// counter-clockwise polygon.
+func outward(poly []Point, i int) Vector {
+	return edge(poly, i).Normal().Inverse()
+}
@@ -0,0 +1,63 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"math"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+// A Material describes how a surface responds to a body that hits it.
+// The zero Material is a frictionless surface that stops motion into it,
+// and along which bodies slide freely.
+type Material struct {
+	// Friction is the coefficient of friction.  Friction slows motion
+	// along the surface by this fraction of the motion into it, so ice
+	// has little friction and sticky walls have a lot.
+	Friction float64
+
+	// Restitution is the fraction of the motion into the surface that
+	// is reflected back away from it.  A bouncy pad has a restitution
+	// near 1; a restitution of 0 stops the motion into the surface.
+	Restitution float64
+
+	// SurfaceVelocity is the speed at which the surface moves along
+	// itself, in the direction from the first point of the segment to the
+	// second, as for a conveyor belt.  Friction drags bodies in contact
+	// with the surface toward its velocity.  Since moves do not know the
+	// passage of time, SurfaceVelocity is only used by the velocity
+	// response of a World or a Character.
+	SurfaceVelocity float64
+}
+
+// respond returns the motion of a body after hitting a surface with the
+// given normal, pointing from the surface toward the body, given its motion
+// before, and the velocity of the surface along the surface.  Motion that
+// is not into the surface is unchanged.
+func (m Material) respond(v, n, surface Vector) Vector {
+	rel := v.Minus(surface)
+	vn := rel.Dot(n)
+	if vn >= 0 {
+		return v
+	}
+	vt := rel.Minus(n.ScaledBy(vn))
+	if speed := vt.Magnitude(); speed > 0 {
+		vt = vt.ScaledBy(math.Max(0, speed+m.Friction*vn) / speed)
+	}
+	return surface.Plus(vt).Minus(n.ScaledBy(m.Restitution * vn))
+}
+
+// velocity returns the velocity of a body after a contact, given its
+// velocity before.
+func (c Contact) velocity(v Vector) Vector {
+	return c.Material.respond(v, c.Normal, c.surfaceVelocity())
+}
+
+// surfaceVelocity returns the velocity of the surface of a contact.
+func (c Contact) surfaceVelocity() Vector {
+	// The surface runs from the first point of a segment to the
+	// second, which is clockwise from the segment normal.
+	along := Vector{c.Normal[1], -c.Normal[0]}
+	return along.ScaledBy(c.Material.SurfaceVelocity)
+}
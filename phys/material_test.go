@@ -0,0 +1,128 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+// floorGrid returns a grid of the floor of the ledge segments,
+// with the given material.
+func floorGrid(m Material) *Grid {
+	g := NewGrid(ledgeSegs[:1], 20)
+	g.SetMaterial(0, m)
+	return g
+}
+
+func TestMoveMaterial(t *testing.T) {
+	// The circle hits the floor after 12.5 units of its move, and the
+	// remaining (22.5, -30) responds to the floor's material.
+	c := Circle{Center: Point{50, 20}, Radius: 10}
+	v := Vector{30, -40}
+	tests := []struct {
+		m   Material
+		end Point
+	}{
+		{Material{}, Point{80, 10}},
+		{Material{Friction: 0.5}, Point{65, 10}},
+		{Material{Friction: 1}, Point{57.5, 10}},
+		{Material{Friction: 2}, Point{57.5, 10}},
+		{Material{Restitution: 1}, Point{80, 40}},
+		{Material{Restitution: 0.5}, Point{80, 25}},
+		{Material{Friction: 0.5, Restitution: 0.5}, Point{65, 25}},
+		// Moves do not know the passage of time, so a conveyor
+		// does not carry them.
+		{Material{SurfaceVelocity: 100}, Point{80, 10}},
+		{Material{Friction: 1, SurfaceVelocity: 100}, Point{57.5, 10}},
+	}
+	for _, test := range tests {
+		c2, cs, err := floorGrid(test.m).MoveCircle(c, v, nil)
+		if err != nil || len(cs) == 0 {
+			t.Errorf("Expected moving by %v to hit the floor with %+v, got %v, %v", v, test.m, cs, err)
+			continue
+		}
+		if cs[0].Material != test.m {
+			t.Errorf("Expected the contact with the floor to have material %+v, got %+v", test.m, cs[0].Material)
+		}
+		if c2.Center.Distance(test.end) > 1e-6 {
+			t.Errorf("Expected moving by %v with %+v to end at %v, got %v", v, test.m, test.end, c2.Center)
+		}
+	}
+}
+
+func TestWorldMaterial(t *testing.T) {
+	tests := []struct {
+		m Material
+		v Vector
+		// vx is the body's speed along the floor after a second.
+		vx float64
+	}{
+		{Material{}, Vector{100, 0}, 100},
+		{Material{Friction: 0.5}, Vector{100, 0}, 0},
+		{Material{Friction: 0.5}, Vector{-100, 0}, 0},
+		// A conveyor belt carries a body resting on it.
+		{Material{Friction: 1, SurfaceVelocity: 50}, Vector{}, 50},
+		{Material{Friction: 1, SurfaceVelocity: -50}, Vector{}, -50},
+		{Material{Friction: 1, SurfaceVelocity: 50}, Vector{100, 0}, 50},
+		// Without friction, it cannot.
+		{Material{SurfaceVelocity: 50}, Vector{}, 0},
+	}
+	for _, test := range tests {
+		w := NewWorld(floorGrid(test.m), dt)
+		w.Gravity = Vector{0, -500}
+		b := &Body{Ellipse: Ellipse{Center: Point{100, 10 + Threshold}, Radii: Vector{5, 10}}, Velocity: test.v}
+		w.Add(b)
+		stepWorld(w, 60)
+		if !OnGround(b.Contacts) || math.Abs(b.Velocity[0]-test.vx) > 1e-6 {
+			t.Errorf("Expected a body moving at %v on %+v to move at %g, got %v, on the ground=%t",
+				test.v, test.m, test.vx, b.Velocity, OnGround(b.Contacts))
+		}
+	}
+}
+
+func TestWorldRestitution(t *testing.T) {
+	for _, r := range []float64{0, 0.5, 1} {
+		w := NewWorld(floorGrid(Material{Restitution: r}), dt)
+		w.Gravity = Vector{0, -500}
+		b := &Body{Ellipse: Ellipse{Center: Point{100, 50}, Radii: Vector{5, 10}}}
+		w.Add(b)
+		for i := 0; i < 60 && len(b.Contacts) == 0; i++ {
+			vy := b.Velocity[1] + w.Gravity[1]*dt
+			w.Step()
+			if len(b.Contacts) > 0 && math.Abs(b.Velocity[1]+r*vy) > 1e-6 {
+				t.Errorf("Expected a body hitting the floor at %g with restitution %g to bounce at %g, got %g",
+					vy, r, -r*vy, b.Velocity[1])
+			}
+		}
+		if len(b.Contacts) == 0 {
+			t.Errorf("Expected a body to fall onto the floor with restitution %g", r)
+		}
+	}
+}
+
+func TestCharacterMaterial(t *testing.T) {
+	body := Ellipse{Center: Point{100, 20 + Threshold}, Radii: Vector{10, 20}}
+
+	// A character standing on a conveyor belt is carried along,
+	// and does not fall asleep.
+	c := NewCharacter(body, nil)
+	c.Grid = floorGrid(Material{Friction: 1, SurfaceVelocity: 50})
+	stepCharacter(t, c, Input{}, 60)
+	if !c.Grounded || c.Asleep || math.Abs(c.Velocity[0]-50) > 1e-6 {
+		t.Errorf("Expected a character on a conveyor to move with it at 50, got %v, grounded=%t, asleep=%t",
+			c.Velocity, c.Grounded, c.Asleep)
+	}
+
+	// A character landing on a bouncy pad bounces back up.
+	c = NewCharacter(Ellipse{Center: Point{100, 70}, Radii: body.Radii}, nil)
+	c.Grid = floorGrid(Material{Restitution: 1})
+	for i := 0; i < 60 && len(c.Contacts) == 0; i++ {
+		stepCharacter(t, c, Input{}, 1)
+	}
+	if len(c.Contacts) == 0 || c.Velocity[1] <= 0 {
+		t.Errorf("Expected a character landing on a bouncy pad to bounce, got %v, %v", c.Contacts, c.Velocity)
+	}
+}
@@ -0,0 +1,138 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"math"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+const (
+	// DefaultMaxSlope is the default MaxSlope: acos(0.9), or about
+	// 26 degrees, which is the steepest ground before MaxSlope was added.
+	DefaultMaxSlope = 0.45102681179626236
+
+	// DefaultMaxSlides is the default MaxSlides.
+	DefaultMaxSlides = 10
+
+	// DefaultSkinWidth is the default SkinWidth.
+	DefaultSkinWidth = Threshold
+)
+
+// MoveOptions control how a body is moved.  A nil *MoveOptions, or a
+// zero value for any of its fields, selects the default for that field.
+type MoveOptions struct {
+	// MaxSlope is the angle, in radians, between up and the normal of
+	// the steepest surface that is considered to be the ground.  In effect,
+	// this determines how steep of a hill a body can climb: on steeper
+	// surfaces it is not "on the ground," and so it slides down them.
+	MaxSlope float64
+
+	// MaxSlides is the maximum number of times that a body may slide
+	// along a surface during a single move.  A body that slides into a
+	// wedge between two segments stops, but sliding along many short
+	// segments, such as those of a curve, can take many slides, so the
+	// number of slides is limited.
+	MaxSlides int
+
+	// SkinWidth is the distance short of a surface at which a body stops
+	// when it hits it.  Keeping a small gap prevents rounding errors from
+	// embedding the body in the surface.
+	SkinWidth float64
+
+	// Up is the direction opposite to gravity.  It need not be a unit vector.
+	// The ground is beneath a body and the ceiling is above it.  The default
+	// is positive Y.
+	Up Vector
+
+	// UpAt, if non-nil, gives the up direction at a point, overriding Up.
+	// Contacts are classified using the up direction at the point of the
+	// contact, so gravity need not be uniform.  For example, RadialUp gives
+	// gravity that pulls toward the center of a planetoid.  If UpAt returns
+	// the zero vector then Up is used instead.
+	UpAt func(Point) Vector
+
+	// Filter is the filter of the body.  The body only collides with
+	// the segments whose filters collide with it.  Segments given
+	// in a slice, rather than in a Grid, have the zero Filter.
+	Filter Filter
+
+	// Ignore are the indices of segments that the body does not collide
+	// with.  For example, a character dropping through a one-way platform
+	// ignores it until it is clear of it.
+	Ignore []int
+
+	// Depenetrate determines whether a circular or elliptical body is first
+	// pushed out of any segments that it overlaps, as by ResolveOverlap.  A
+	// body that begins a move overlapping a segment can otherwise become
+	// stuck in it or pass through it.
+	Depenetrate bool
+}
+
+// withDefaults returns a copy of the options with defaults filled in.
+func (o *MoveOptions) withDefaults() MoveOptions {
+	var opts MoveOptions
+	if o != nil {
+		opts = *o
+	}
+	if opts.MaxSlope == 0 {
+		opts.MaxSlope = DefaultMaxSlope
+	}
+	if opts.MaxSlides == 0 {
+		opts.MaxSlides = DefaultMaxSlides
+	}
+	if opts.SkinWidth == 0 {
+		opts.SkinWidth = DefaultSkinWidth
+	}
+	if opts.Up.NearZero() {
+		opts.Up = Vector{0, 1}
+	}
+	opts.Up = opts.Up.Unit()
+	return opts
+}
+
+// skin returns the skin width along a normal, which is the same along
+// every normal for bodies that move in the world.
+func (o *MoveOptions) skin(Vector) float64 {
+	return o.SkinWidth
+}
+
+// filtered returns the segments of a set that a body moved with the
+// options collides with.
+func (o *MoveOptions) filtered(segs segmentSet) segmentSet {
+	return filteredSet{segs, o.Filter, o.Ignore}
+}
+
+// RadialUp returns a function, suitable for the UpAt option, that gives
+// up as the direction away from a center point.
+func RadialUp(center Point) func(Point) Vector {
+	return func(p Point) Vector {
+		return p.Minus(center)
+	}
+}
+
+// up returns the unit up direction at a point.
+func (o *MoveOptions) up(p Point) Vector {
+	if o.UpAt == nil {
+		return o.Up
+	}
+	up := o.UpAt(p)
+	if up.NearZero() {
+		return o.Up
+	}
+	return up.Unit()
+}
+
+// contactKind returns the kind of a contact at the given point with the
+// given normal.
+func (o *MoveOptions) contactKind(p Point, n Vector) ContactKind {
+	cos := math.Cos(o.MaxSlope)
+	switch up := n.Dot(o.up(p)); {
+	case up >= cos:
+		return Ground
+	case up <= -cos:
+		return Ceiling
+	}
+	return Wall
+}
@@ -0,0 +1,96 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+func TestContactKind(t *testing.T) {
+	// The normals of a gentle and a steep slope, 0.3 and 1 radians
+	// from up.
+	gentle := Vector{math.Sin(0.3), math.Cos(0.3)}
+	steep := Vector{math.Sin(1), math.Cos(1)}
+	tests := []struct {
+		opts MoveOptions
+		p    Point
+		n    Vector
+		kind ContactKind
+	}{
+		{MoveOptions{}, Point{}, Vector{0, 1}, Ground},
+		{MoveOptions{}, Point{}, Vector{0, -1}, Ceiling},
+		{MoveOptions{}, Point{}, Vector{1, 0}, Wall},
+		{MoveOptions{}, Point{}, gentle, Ground},
+		{MoveOptions{}, Point{}, gentle.Inverse(), Ceiling},
+		{MoveOptions{}, Point{}, steep, Wall},
+		{MoveOptions{}, Point{}, steep.Inverse(), Wall},
+		// By default, the ground is no steeper than where the y of its
+		// normal is 0.9.
+		{MoveOptions{}, Point{}, Vector{math.Sqrt(1 - 0.91*0.91), 0.91}, Ground},
+		{MoveOptions{}, Point{}, Vector{math.Sqrt(1 - 0.89*0.89), 0.89}, Wall},
+		{MoveOptions{MaxSlope: 1.1}, Point{}, steep, Ground},
+		{MoveOptions{MaxSlope: 0.2}, Point{}, gentle, Wall},
+
+		// Up need not be a unit vector.
+		{MoveOptions{Up: Vector{0, -10}}, Point{}, Vector{0, 1}, Ceiling},
+		{MoveOptions{Up: Vector{0, -10}}, Point{}, Vector{0, -1}, Ground},
+		{MoveOptions{Up: Vector{5, 0}}, Point{}, Vector{1, 0}, Ground},
+		{MoveOptions{Up: Vector{5, 0}}, Point{}, Vector{0, 1}, Wall},
+
+		// Up is away from the center of a planetoid at the origin.
+		{MoveOptions{UpAt: RadialUp(Point{})}, Point{0, 10}, Vector{0, 1}, Ground},
+		{MoveOptions{UpAt: RadialUp(Point{})}, Point{0, -10}, Vector{0, -1}, Ground},
+		{MoveOptions{UpAt: RadialUp(Point{})}, Point{10, 0}, Vector{1, 0}, Ground},
+		{MoveOptions{UpAt: RadialUp(Point{})}, Point{10, 0}, Vector{0, 1}, Wall},
+		{MoveOptions{UpAt: RadialUp(Point{})}, Point{0, 10}, Vector{0, -1}, Ceiling},
+		// At the center, there is no up, so Up is used.
+		{MoveOptions{UpAt: RadialUp(Point{})}, Point{}, Vector{0, 1}, Ground},
+		{MoveOptions{Up: Vector{1, 0}, UpAt: RadialUp(Point{})}, Point{}, Vector{1, 0}, Ground},
+	}
+	for _, test := range tests {
+		opts := test.opts.withDefaults()
+		if k := opts.contactKind(test.p, test.n); k != test.kind {
+			t.Errorf("Expected a contact at %v with normal %v, up %v, and max slope %g to be %v, got %v",
+				test.p, test.n, opts.up(test.p), opts.MaxSlope, test.kind, k)
+		}
+	}
+}
+
+func TestMoveContactKind(t *testing.T) {
+	// The circle moves from the center of the box room to hit one of
+	// its sides.
+	tests := []struct {
+		v    Vector
+		opts MoveOptions
+		kind ContactKind
+	}{
+		{Vector{0, -100}, MoveOptions{}, Ground},
+		{Vector{0, 100}, MoveOptions{}, Ceiling},
+		{Vector{100, 0}, MoveOptions{}, Wall},
+		{Vector{0, -100}, MoveOptions{Up: Vector{0, -1}}, Ceiling},
+		{Vector{100, 0}, MoveOptions{Up: Vector{-1, 0}}, Ground},
+		// Up is away from the center of the room, so its sides
+		// are all above the body.
+		{Vector{0, -100}, MoveOptions{UpAt: RadialUp(Point{50, 50})}, Ceiling},
+		{Vector{100, 0}, MoveOptions{UpAt: RadialUp(Point{50, 50})}, Ceiling},
+		// Up is away from a point far below the room.
+		{Vector{0, -100}, MoveOptions{UpAt: RadialUp(Point{50, -1000})}, Ground},
+		{Vector{0, 100}, MoveOptions{UpAt: RadialUp(Point{50, -1000})}, Ceiling},
+	}
+	for _, test := range tests {
+		opts := test.opts
+		_, cs, err := MoveCircle(Circle{Center: Point{50, 50}, Radius: 10}, test.v, box, &opts)
+		if err != nil || len(cs) == 0 {
+			t.Errorf("Expected moving by %v to hit a side of the room, got %v, %v", test.v, cs, err)
+			continue
+		}
+		if k := cs[0].Kind; k != test.kind {
+			o := opts.withDefaults()
+			up := o.up(cs[0].Point)
+			t.Errorf("Expected moving by %v with up %v to hit the %v, got the %v", test.v, up, test.kind, k)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"fmt"
+	"sort"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+// OverlapCircle returns the indices, in increasing order, of the segments
+// that intersect a circle.  Segments that touch the edge of the circle
+// are included.
+func OverlapCircle(c Circle, segs []Segment) []int {
+	return overlapSegments(c, segmentSlice(segs))
+}
+
+// OverlapEllipse returns the indices, in increasing order, of the segments
+// that intersect an ellipse.  Segments that touch the edge of the ellipse
+// are included.
+func OverlapEllipse(e Ellipse, segs []Segment) []int {
+	return overlapSegments(e, segmentSlice(segs))
+}
+
+// OverlapRectangle returns the indices, in increasing order, of the
+// segments that intersect a rectangle.  Segments that touch the edge of
+// the rectangle are included.
+func OverlapRectangle(r Rectangle, segs []Segment) []int {
+	return overlapSegments(r, segmentSlice(segs))
+}
+
+// OverlapCircle is like the OverlapCircle function, but it returns
+// segments of the grid.
+func (g *Grid) OverlapCircle(c Circle) []int {
+	return overlapSegments(c, g)
+}
+
+// OverlapEllipse is like the OverlapEllipse function, but it returns
+// segments of the grid.
+func (g *Grid) OverlapEllipse(e Ellipse) []int {
+	return overlapSegments(e, g)
+}
+
+// OverlapRectangle is like the OverlapRectangle function, but it returns
+// segments of the grid.
+func (g *Grid) OverlapRectangle(r Rectangle) []int {
+	return overlapSegments(r, g)
+}
+
+// OverlapCircle returns the indices of the static segments of the world
+// that intersect a circle, as for the OverlapCircle function, and the
+// bodies that overlap it, in increasing order of their IDs.  As with
+// triggers, bodies that only touch the circle are not included.
+func (w *World) OverlapCircle(c Circle) ([]int, []*Body) {
+	return w.overlap(c)
+}
+
+// OverlapEllipse is like OverlapCircle, but for an ellipse.
+func (w *World) OverlapEllipse(e Ellipse) ([]int, []*Body) {
+	return w.overlap(e)
+}
+
+// OverlapRectangle is like OverlapCircle, but for a rectangle.
+func (w *World) OverlapRectangle(r Rectangle) ([]int, []*Body) {
+	return w.overlap(r)
+}
+
+// overlap returns the static segments and the bodies overlapping a region.
+func (w *World) overlap(r Region) ([]int, []*Body) {
+	var is []int
+	if w.Grid != nil {
+		is = overlapSegments(r, w.Grid)
+	}
+	var bs []*Body
+	for _, b := range w.bodies {
+		if overlaps(b.Ellipse, r) {
+			bs = append(bs, b)
+		}
+	}
+	return is, bs
+}
+
+// overlapSegments returns the indices, in increasing order, of the
+// segments that intersect a region.
+func overlapSegments(r Region, segs segmentSet) []int {
+	var is []int
+	segs.near(r.Bounds(), func(i int, s Segment) {
+		if segmentOverlaps(s, r) {
+			is = append(is, i)
+		}
+	})
+	sort.Ints(is)
+	return is
+}
+
+// segmentOverlaps returns true if a segment intersects or touches a region.
+func segmentOverlaps(s Segment, r Region) bool {
+	switch r := r.(type) {
+	case Rectangle:
+		return segmentTouches(s, r.Canon())
+	case Circle:
+		return s.NearestPoint(r.Center).SquaredDistance(r.Center) <= r.Radius*r.Radius
+	case Ellipse:
+		tr, _ := unitCircleSpace(r)
+		c := tr.ApplyPoint(r.Center)
+		return tr.ApplySegment(s).NearestPoint(c).SquaredDistance(c) <= 1
+	case Polygon:
+		if r.ContainsNonZero(s[0]) {
+			return true
+		}
+		for i := range r {
+			if _, _, _, hit := s.Intersect(r.Edge(i)); hit {
+				return true
+			}
+		}
+		return false
+	}
+	panic(fmt.Sprintf("unsupported region type %T", r))
+}
@@ -0,0 +1,112 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"testing"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+// overlapSegs are two horizontal segments, a vertical segment,
+// and a diagonal segment off by itself.
+var overlapSegs = []Segment{
+	{{0, 0}, {10, 0}},
+	{{0, 5}, {10, 5}},
+	{{20, 0}, {20, 10}},
+	{{30, 30}, {40, 40}},
+}
+
+func TestOverlap(t *testing.T) {
+	tests := []struct {
+		r    Region
+		segs []int
+	}{
+		{Circle{Center: Point{5, 0}, Radius: 1}, []int{0}},
+		{Circle{Center: Point{5, 2.5}, Radius: 2.4}, nil},
+		{Circle{Center: Point{100, 100}, Radius: 10}, nil},
+		{Circle{Center: Point{20, 20}, Radius: 100}, []int{0, 1, 2, 3}},
+		// Segments that touch the edge are included.
+		{Circle{Center: Point{5, 2.5}, Radius: 2.5}, []int{0, 1}},
+		{Circle{Center: Point{15, 5}, Radius: 5}, []int{1, 2}},
+
+		{Ellipse{Center: Point{5, 2.5}, Radii: Vector{1, 2.4}}, nil},
+		{Ellipse{Center: Point{35, 35}, Radii: Vector{1, 2}}, []int{3}},
+		{Ellipse{Center: Point{5, 2.5}, Radii: Vector{1, 2.5}}, []int{0, 1}},
+		{Ellipse{Center: Point{25, 5}, Radii: Vector{5, 1}}, []int{2}},
+
+		{NewRectangle(Point{0, 1}, Point{10, 4}), nil},
+		{NewRectangle(Point{32, 30}, Point{35, 33}), []int{3}},
+		{NewRectangle(Point{-5, -5}, Point{50, 50}), []int{0, 1, 2, 3}},
+		{NewRectangle(Point{0, 0}, Point{10, 5}), []int{0, 1}},
+		{NewRectangle(Point{20, 10}, Point{25, 15}), []int{2}},
+		{NewRectangle(Point{11, -1}, Point{19, 11}), nil},
+	}
+	for _, test := range tests {
+		var fn, grid []int
+		g := NewGrid(overlapSegs, 10)
+		w := NewWorld(g, dt)
+		var world []int
+		switch r := test.r.(type) {
+		case Circle:
+			fn, grid = OverlapCircle(r, overlapSegs), g.OverlapCircle(r)
+			world, _ = w.OverlapCircle(r)
+		case Ellipse:
+			fn, grid = OverlapEllipse(r, overlapSegs), g.OverlapEllipse(r)
+			world, _ = w.OverlapEllipse(r)
+		case Rectangle:
+			fn, grid = OverlapRectangle(r, overlapSegs), g.OverlapRectangle(r)
+			world, _ = w.OverlapRectangle(r)
+		}
+		if !equalInts(fn, test.segs) {
+			t.Errorf("Expected %v to overlap segments %v, got %v", test.r, test.segs, fn)
+		}
+		if !equalInts(grid, test.segs) {
+			t.Errorf("Expected %v to overlap grid segments %v, got %v", test.r, test.segs, grid)
+		}
+		if !equalInts(world, test.segs) {
+			t.Errorf("Expected %v to overlap world segments %v, got %v", test.r, test.segs, world)
+		}
+	}
+}
+
+func TestWorldOverlap(t *testing.T) {
+	w := NewWorld(nil, dt)
+	for _, c := range []Point{{0, 0}, {20, 0}, {10, 0}, {10, 15}} {
+		w.Add(&Body{Ellipse: Ellipse{Center: c, Radii: Vector{2, 4}}})
+	}
+	tests := []struct {
+		r      Region
+		bodies []BodyID
+	}{
+		{Circle{Center: Point{10, 0}, Radius: 1}, []BodyID{3}},
+		{Circle{Center: Point{10, 0}, Radius: 9}, []BodyID{1, 2, 3}},
+		// Bodies that only touch are not included.
+		{Circle{Center: Point{10, 0}, Radius: 8}, []BodyID{3}},
+		{Ellipse{Center: Point{10, 7.5}, Radii: Vector{1, 4}}, []BodyID{3, 4}},
+		{Ellipse{Center: Point{10, 7.5}, Radii: Vector{1, 3.5}}, nil},
+		{Ellipse{Center: Point{10, 8}, Radii: Vector{1, 3.5}}, []BodyID{4}},
+		{NewRectangle(Point{-10, -10}, Point{30, 30}), []BodyID{1, 2, 3, 4}},
+		{NewRectangle(Point{2, -4}, Point{8, 4}), nil},
+		{NewRectangle(Point{1.9, -4}, Point{8, 4}), []BodyID{1}},
+	}
+	for _, test := range tests {
+		var segs []int
+		var bs []*Body
+		switch r := test.r.(type) {
+		case Circle:
+			segs, bs = w.OverlapCircle(r)
+		case Ellipse:
+			segs, bs = w.OverlapEllipse(r)
+		case Rectangle:
+			segs, bs = w.OverlapRectangle(r)
+		}
+		var ids []BodyID
+		for _, b := range bs {
+			ids = append(ids, b.ID())
+		}
+		if len(segs) != 0 || !equalIDs(ids, test.bodies) {
+			t.Errorf("Expected %v to overlap bodies %v, got %v and segments %v", test.r, test.bodies, ids, segs)
+		}
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
-	"math"
+	"log"
 	"os"
 	"time"
 
@@ -20,20 +20,16 @@ const (
 	width  = 640
 	height = 480
 
-	speed   = 5
-	gravity = -1
-
-	// StopFactor determines when an object has stopped moving.
-	// If the distance moved is less than stopFactor times the fall
-	// velocity, then the object is considered to be stopped.
-	stopFactor       = 0.25
-	terminalVelocity = -20
+	// Dt is the time between ticks in seconds.
+	dt = 0.04
 )
 
 var (
-	move Vector
-	fall float64
-	body = Ellipse{Center: Point{200, 200}, Radii: Vector{25, 50}}
+	// Input is the current control input, set from the keyboard.
+	input phys.Input
+
+	// Left and right are true if the corresponding arrow key is held.
+	left, right bool
 
 	// Segs is the set of segments defining obstacles.
 	segs = []Segment{
@@ -43,17 +39,22 @@ var (
 		{{width - 1, height - 1}, {0, height - 1}},
 	}
 
+	// OneWay records which segments are one-way.  Typing o toggles
+	// whether the most recently added segment is one-way.
+	oneWay = make([]bool, len(segs))
+
+	// Hero is the character controlled by the keyboard.
+	hero = phys.NewCharacter(Ellipse{Center: Point{200, 200}, Radii: Vector{25, 50}}, segs)
+
 	// Click is the position of the latest mouse click.
 	click = Point{-1, -1}
 
+	// Selection is the corner at which a selection began with the right
+	// mouse button.  The segments touched by the selection are highlighted.
+	selection = Point{-1, -1}
+
 	// Cursor is the current cursor position.
 	cursor Point
-
-	// Stopped is true if the body has effectively stopped moving.
-	stopped bool
-
-	// OnGround is true if the body is not falling.
-	onGround bool
 )
 
 func main() {
@@ -71,7 +72,7 @@ func mainLoop() {
 
 	drawScene(win)
 
-	tick := time.NewTicker(40 * time.Millisecond)
+	tick := time.NewTicker(time.Duration(dt * float64(time.Second)))
 	for {
 		select {
 		case ev, ok := <-win.EventChan():
@@ -98,18 +99,8 @@ func mainLoop() {
 			}
 
 		case <-tick.C:
-			if !stopped {
-				if !onGround && NearZero(move[1]) {
-					fall = math.Max(fall+gravity, terminalVelocity)
-				}
-				vel := move.Plus(Vector{0, fall})
-				start := body.Center
-				body, onGround = phys.MoveEllipse(body, vel, segs)
-				dist := start.Minus(body.Center).Magnitude()
-				stopped = move.NearZero() && dist < stopFactor*math.Abs(fall)
-				if onGround {
-					fall = gravity
-				}
+			if err := hero.Step(input, dt); err != nil {
+				log.Println(err)
 			}
 			drawScene(win)
 		}
@@ -124,6 +115,8 @@ func mouseDown(ev wde.MouseButtonEvent) {
 	switch ev.Which {
 	case wde.LeftButton:
 		click = Point{float64(ev.Where.X), float64(height - ev.Where.Y - 1)}
+	case wde.RightButton:
+		selection = Point{float64(ev.Where.X), float64(height - ev.Where.Y - 1)}
 	}
 }
 
@@ -131,7 +124,11 @@ func mouseUp(ev wde.MouseButtonEvent) {
 	switch ev.Which {
 	case wde.LeftButton:
 		segs = append(segs, Segment{click, cursor})
+		oneWay = append(oneWay, false)
+		segsChanged()
 		click = Point{-1, -1}
+	case wde.RightButton:
+		selection = Point{-1, -1}
 	}
 }
 
@@ -140,30 +137,75 @@ func keyTyped(ev wde.KeyTypedEvent) {
 	case "d":
 		if len(segs) > 4 {
 			segs = segs[:len(segs)-1]
+			oneWay = oneWay[:len(oneWay)-1]
+			segsChanged()
 		}
+	case "o":
+		if len(segs) > 4 {
+			oneWay[len(oneWay)-1] = !oneWay[len(oneWay)-1]
+			segsChanged()
+		}
+	case "g":
+		// Flip gravity.
+		if hero.Options.Up.NearZero() {
+			hero.Options.Up = Vector{0, 1}
+		}
+		hero.Options.Up = hero.Options.Up.ScaledBy(-1)
+		hero.Wake()
+		setMove()
 	}
 }
 
+// SegsChanged updates the hero's grid after the segments change.
+func segsChanged() {
+	hero.Grid = phys.NewGrid(segs, 50)
+	for i, o := range oneWay {
+		hero.Grid.SetOneWay(i, o)
+	}
+	hero.Wake()
+}
+
 func keyDown(ev wde.KeyEvent) {
 	switch ev.Key {
 	case "left_arrow":
-		move[0] = -speed
+		left = true
 	case "right_arrow":
-		move[0] = speed
+		right = true
 	case "up_arrow":
-		move[1] = speed - gravity
+		input.Jump = true
 	case "down_arrow":
-		move[1] = -speed
+		input.Down = true
 	}
-	stopped = false
+	setMove()
 }
 
 func keyUp(ev wde.KeyEvent) {
 	switch ev.Key {
-	case "left_arrow", "right_arrow":
-		move[0] = 0
-	case "up_arrow", "down_arrow":
-		move[1] = 0
+	case "left_arrow":
+		left = false
+	case "right_arrow":
+		right = false
+	case "up_arrow":
+		input.Jump = false
+	case "down_arrow":
+		input.Down = false
+	}
+	setMove()
+}
+
+// SetMove sets the horizontal movement input from the arrow keys.
+// Movement is relative to the hero, so when gravity is flipped, the
+// arrows are swapped to keep them moving in the direction on the screen.
+func setMove() {
+	input.Move = 0
+	if left {
+		input.Move--
+	}
+	if right {
+		input.Move++
+	}
+	if hero.Options.Up[1] < 0 {
+		input.Move = -input.Move
 	}
 }
 
@@ -171,10 +213,27 @@ func drawScene(win wde.Window) {
 	clear(win)
 	cv := ImageCanvas{win.Screen()}
 
-	for _, s := range segs {
+	selected := make(map[int]bool)
+	if selection[0] >= 0 {
+		sel := NewRectangle(selection, cursor)
+		for _, i := range phys.OverlapRectangle(sel, segs) {
+			selected[i] = true
+		}
+		sel.Draw(cv, color.RGBA{B: 255, A: 255})
+	}
+
+	for i, s := range segs {
+		if selected[i] {
+			s.Draw(cv, color.RGBA{R: 255, A: 255})
+			continue
+		}
+		if oneWay[i] {
+			s.Draw(cv, color.Gray{Y: 128})
+			continue
+		}
 		s.Draw(cv, color.Black)
 	}
-	body.Draw(cv, color.Black)
+	hero.Body.Draw(cv, color.Black)
 
 	if click[0] >= 0 {
 		Segment{click, cursor}.Draw(cv, color.RGBA{B: 255, A: 255})
@@ -0,0 +1,246 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	. "github.com/eaburns/quart/geom"
+)
+
+// A PlatformID identifies a platform in a World.
+type PlatformID int
+
+// A Platform is a group of segments that moves through a World, such as an
+// elevator or a rotating wheel.  Platforms are kinematic: they follow their
+// Motion regardless of the bodies in the world.  Bodies standing on a
+// platform are carried along with it, and bodies in its way are pushed.
+type Platform struct {
+	// Segments are the segments of the platform in its own space.
+	Segments []Segment
+
+	// Transform transforms the platform from its own space into the world.
+	Transform Transform
+
+	// Motion is applied to the Transform at each step, after Transform.
+	// For example, a platform moving with velocity v has a Motion of
+	// Translate(v.ScaledBy(dt)) for a timestep dt.
+	Motion Transform
+
+	// Filter is the filter of the platform's segments.
+	Filter Filter
+
+	// OneWay is true if the platform's segments are one-way segments,
+	// as described for Grid.SetOneWay.
+	OneWay bool
+
+	// Material is the material of the platform's segments.
+	Material Material
+
+	// Update, if non-nil, is called for the platform at the start of each
+	// step, before it is moved.  It can be used to change the Motion, for
+	// example, to reverse an elevator at the end of its track.
+	Update func(p *Platform, dt float64)
+
+	// Previous is the Transform at the start of the most recent step.
+	Previous Transform
+
+	id PlatformID
+
+	// World are the segments of the platform in the world.
+	world []Segment
+}
+
+// NewPlatform returns a new platform, with identity Transform and Motion,
+// from segments in the platform's space.
+func NewPlatform(segs []Segment) *Platform {
+	return &Platform{
+		Segments:  segs,
+		Transform: Identity(),
+		Motion:    Identity(),
+	}
+}
+
+// ID returns the ID of the platform.  It is zero if the platform has not
+// been added to a World.
+func (p *Platform) ID() PlatformID {
+	return p.id
+}
+
+// WorldSegments returns the segments of the platform in the world, as of
+// the most recent step.  The returned slice must not be modified.
+func (p *Platform) WorldSegments() []Segment {
+	return p.world
+}
+
+// Interpolate returns the segments of the platform in the world a fraction
+// alpha of the way from their positions at the start of the most recent
+// step to their current positions.  It is used with the return value of
+// Advance to draw platforms smoothly between steps.
+func (p *Platform) Interpolate(alpha float64) []Segment {
+	segs := make([]Segment, len(p.Segments))
+	for i, s := range p.Segments {
+		for j, pt := range s {
+			a, b := p.Previous.ApplyPoint(pt), p.Transform.ApplyPoint(pt)
+			segs[i][j] = a.Plus(b.Minus(a).ScaledBy(alpha))
+		}
+	}
+	return segs
+}
+
+// place computes the world segments of the platform.
+func (p *Platform) place() {
+	p.world = make([]Segment, len(p.Segments))
+	for i, s := range p.Segments {
+		p.world[i] = p.Transform.ApplySegment(s)
+	}
+}
+
+// AddPlatform adds a platform to the world and returns its ID.  IDs are
+// assigned in increasing order and are never reused.
+func (w *World) AddPlatform(p *Platform) PlatformID {
+	w.nextPlatform++
+	p.id = w.nextPlatform
+	p.Previous = p.Transform
+	p.place()
+	w.platforms = append(w.platforms, p)
+	return p.id
+}
+
+// RemovePlatform removes the platform with the given ID from the world.
+// Removing a platform that is not in the world does nothing.
+func (w *World) RemovePlatform(id PlatformID) {
+	for i, p := range w.platforms {
+		if p.id == id {
+			w.platforms = append(w.platforms[:i], w.platforms[i+1:]...)
+			return
+		}
+	}
+}
+
+// Platform returns the platform with the given ID, or nil if there is none.
+func (w *World) Platform(id PlatformID) *Platform {
+	for _, p := range w.platforms {
+		if p.id == id {
+			return p
+		}
+	}
+	return nil
+}
+
+// Platforms returns the platforms of the world in the order that they
+// are stepped.  The returned slice must not be modified.
+func (w *World) Platforms() []*Platform {
+	return w.platforms
+}
+
+// stepPlatform moves a platform by its Motion, carrying the bodies that
+// are standing on it and pushing the bodies in its way.
+func (w *World) stepPlatform(p *Platform, dt float64) {
+	p.Previous = p.Transform
+	if p.Update != nil {
+		p.Update(p, dt)
+	}
+	inv, ok := p.Transform.Inverse()
+	old := platformSet{p, p.world}
+	p.Transform = p.Transform.Then(p.Motion)
+	p.place()
+	if !ok {
+		return
+	}
+	// Disp returns the displacement of the point of the platform
+	// that was at pt at the start of the step.
+	disp := func(pt Point) Vector {
+		return p.Transform.ApplyPoint(inv.ApplyPoint(pt)).Minus(pt)
+	}
+
+	for _, b := range w.bodies {
+		if !p.Filter.Collides(b.Options.Filter) {
+			continue
+		}
+		if g, ok := GroundContact(b.Contacts); ok && g.Platform == p.id {
+			d := disp(g.Point)
+			w.shove(b, d, g.Normal, p)
+			w.unembed(b, p)
+			b.ride = p.id
+			b.rideVelocity = d.ScaledBy(1 / dt)
+			continue
+		}
+
+		// The platform pushes the body by the part of its displacement
+		// toward the body that remains after they touch.  This is found
+		// by sweeping the body against the platform, in the opposite
+		// direction of the platform's movement.
+		d := disp(b.Ellipse.Center)
+		if d.NearZero() {
+			continue
+		}
+		opts := w.options(b)
+		_, cs, _ := moveEllipse(b.Ellipse, d.Inverse(), old, opts)
+		if len(cs) == 0 {
+			continue
+		}
+		c := cs[0]
+		into := d.Inverse().ScaledBy(1 - c.Time).Dot(c.Normal)
+		if into >= 0 {
+			continue
+		}
+		w.shove(b, c.Normal.ScaledBy(-into), c.Normal, p)
+		w.unembed(b, p)
+		c.Platform = p.id
+		c.Point = c.Point.Plus(disp(c.Point))
+		c.Time = 0
+		b.pushes = append(b.pushes, c)
+
+		// A body pushed from below is carried from the next step
+		// on.  Otherwise it moves at least as fast as the platform.
+		if c.Kind == Ground {
+			continue
+		}
+		if rel := b.Velocity.Minus(d.ScaledBy(1 / dt)).Dot(c.Normal); rel < 0 {
+			b.Velocity = b.Velocity.Minus(c.Normal.ScaledBy(rel))
+		}
+	}
+}
+
+// shove moves a body that is touching a platform, with the given contact
+// normal, along a vector.  The body is crushed if it is blocked by an
+// obstacle that faces the platform, since the platform would then overlap it.
+func (w *World) shove(b *Body, v, n Vector, p *Platform) {
+	set := &worldSet{w: w, mover: b, exclude: p}
+	var cs []Contact
+	b.Ellipse, cs, _ = moveEllipse(b.Ellipse, v, set, w.options(b))
+	for _, c := range cs {
+		if c.Normal.Dot(n) < 0 {
+			b.Crushed = true
+		}
+	}
+}
+
+// unembed pushes a body out of a platform that it overlaps.  The sweep
+// used to find how far a platform pushes a body is only exact for
+// platforms that do not rotate, and even then the body may be left
+// touching the platform, which would let it pass through.
+func (w *World) unembed(b *Body, p *Platform) {
+	_, v, _ := resolveEllipse(b.Ellipse, platformSet{p, p.world})
+	if !v.NearZero() {
+		w.shove(b, v, v.Unit(), p)
+	}
+}
+
+// A platformSet is the set of segments of a platform.
+type platformSet struct {
+	p    *Platform
+	segs []Segment
+}
+
+func (ps platformSet) near(r Rectangle, f func(int, Segment)) {
+	segmentSlice(ps.segs).near(r, f)
+}
+
+func (ps platformSet) props(int) segmentProps {
+	return ps.p.props()
+}
+
+// props returns the properties of the platform's segments.
+func (p *Platform) props() segmentProps {
+	return segmentProps{filter: p.Filter, oneWay: p.OneWay, material: p.Material}
+}
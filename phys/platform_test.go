@@ -0,0 +1,121 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+// platformWorld returns a world with the floor of the ledge segments,
+// downward gravity, and a platform with the given segments at a point,
+// moving with a velocity.
+func platformWorld(segs []Segment, at Point, v Vector) (*World, *Platform) {
+	w := NewWorld(NewGrid(ledgeSegs[:1], 20), dt)
+	w.Gravity = Vector{0, -500}
+	p := NewPlatform(segs)
+	p.Transform = Translate(Vector(at))
+	p.Motion = Translate(v.ScaledBy(dt))
+	w.AddPlatform(p)
+	return w, p
+}
+
+func TestPlatformCarry(t *testing.T) {
+	deck := []Segment{{{-20, 0}, {20, 0}}}
+	tests := []Vector{
+		{30, 0},
+		{-30, 0},
+		{0, 30},
+		{0, -15},
+		{30, 30},
+	}
+	for _, v := range tests {
+		w, p := platformWorld(deck, Point{100, 40}, v)
+		b := &Body{Ellipse: Ellipse{Center: Point{100, 51}, Radii: Vector{5, 10}}}
+		w.Add(b)
+		// The body falls onto the platform, and then is carried.
+		stepWorld(w, 30)
+		start := b.Ellipse.Center
+		stepWorld(w, 30)
+		g, ok := GroundContact(b.Contacts)
+		if !ok || g.Platform != p.ID() || b.Err != nil {
+			t.Errorf("Expected a body on a platform moving at %v to stand on it, got %v, %v", v, b.Contacts, b.Err)
+			continue
+		}
+		top := p.WorldSegments()[0][0][1]
+		if bottom := b.Ellipse.Center[1] - b.Ellipse.Radii[1]; math.Abs(bottom-top) > 0.1 {
+			t.Errorf("Expected a body on a platform moving at %v to stay on its top at %g, got %g", v, top, bottom)
+		}
+		if d := b.Ellipse.Center.Minus(start); !d.NearlyEquals(v.ScaledBy(30 * dt)) {
+			t.Errorf("Expected a body on a platform moving at %v to be carried with it by %v, got %v",
+				v, v.ScaledBy(30*dt), d)
+		}
+	}
+}
+
+func TestPlatformPush(t *testing.T) {
+	// A wall, 30 tall, facing right.
+	wall := []Segment{{{0, 30}, {0, 0}}}
+	tests := []struct {
+		v       Vector
+		steps   int
+		crushed bool
+	}{
+		// Pushed along the floor.
+		{Vector{60, 0}, 60, false},
+		// Pushed along the floor and into the wall at its end.
+		{Vector{120, 0}, 150, true},
+	}
+	for _, test := range tests {
+		w, p := platformWorld(wall, Point{50, 0}, test.v)
+		w.Grid = NewGrid(append([]Segment{{{200, 0}, {200, 100}}}, ledgeSegs[:1]...), 20)
+		b := &Body{Ellipse: Ellipse{Center: Point{70, 10 + Threshold}, Radii: Vector{5, 10}}}
+		w.Add(b)
+		crushed := false
+		for i := 0; i < test.steps; i++ {
+			w.Step()
+			if crushed = crushed || b.Crushed; crushed {
+				// The platform overlaps the body that it crushed.
+				continue
+			}
+			x := p.Transform.ApplyPoint(Point{})[0]
+			if left := b.Ellipse.Center[0] - b.Ellipse.Radii[0]; left < x-0.1 {
+				t.Errorf("Expected the platform moving at %v to push the body, got the body at %g and the wall at %g",
+					test.v, left, x)
+				break
+			}
+		}
+		if crushed != test.crushed {
+			t.Errorf("Expected the platform moving at %v to crush the body=%t, got %t", test.v, test.crushed, crushed)
+		}
+	}
+}
+
+func TestPlatformCrush(t *testing.T) {
+	// A ceiling, facing down.
+	ceiling := []Segment{{{20, 0}, {-20, 0}}}
+	tests := []struct {
+		v       Vector
+		crushed bool
+	}{
+		{Vector{0, -30}, true},
+		{Vector{0, 30}, false},
+		// The platform stops short of the body.
+		{Vector{0, -5}, false},
+	}
+	for _, test := range tests {
+		w, _ := platformWorld(ceiling, Point{100, 30}, test.v)
+		b := &Body{Ellipse: Ellipse{Center: Point{100, 10 + Threshold}, Radii: Vector{5, 10}}}
+		w.Add(b)
+		crushed := false
+		for i := 0; i < 60; i++ {
+			w.Step()
+			crushed = crushed || b.Crushed
+		}
+		if crushed != test.crushed {
+			t.Errorf("Expected the platform moving at %v to crush the body=%t, got %t", test.v, test.crushed, crushed)
+		}
+	}
+}
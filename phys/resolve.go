@@ -0,0 +1,101 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	. "github.com/eaburns/quart/geom"
+)
+
+// maxDepenetrations is the maximum number of times that ResolveOverlap
+// pushes a body out of a segment.
+const maxDepenetrations = 10
+
+// ResolveOverlap returns the ellipse pushed out of the segments that it
+// overlaps, and the translation that was applied to it.  The third return
+// value is false if the ellipse still overlaps a segment after being pushed,
+// for example because it is wedged between segments that are too close
+// together for it to fit.
+func ResolveOverlap(e Ellipse, segs []Segment) (Ellipse, Vector, bool) {
+	return resolveEllipse(e, segmentSlice(segs))
+}
+
+// ResolveCircleOverlap is like ResolveOverlap but for circles.
+func ResolveCircleOverlap(c Circle, segs []Segment) (Circle, Vector, bool) {
+	return resolveCircle(c, segmentSlice(segs))
+}
+
+// ResolveOverlap is like the ResolveOverlap function, but it uses the segments of the grid.
+func (g *Grid) ResolveOverlap(e Ellipse) (Ellipse, Vector, bool) {
+	return resolveEllipse(e, g)
+}
+
+// ResolveCircleOverlap is like the ResolveCircleOverlap function, but it uses the segments of the grid.
+func (g *Grid) ResolveCircleOverlap(c Circle) (Circle, Vector, bool) {
+	return resolveCircle(c, g)
+}
+
+func resolveEllipse(e Ellipse, segs segmentSet) (Ellipse, Vector, bool) {
+	tr, inv := unitCircleSpace(e)
+	c := Circle{Center: tr.ApplyPoint(e.Center), Radius: 1}
+	c, push, ok := resolveCircle(c, transformedSet{segs, tr, inv})
+	return Ellipse{Center: inv.ApplyPoint(c.Center), Radii: e.Radii}, inv.ApplyVector(push), ok
+}
+
+// resolveCircle pushes a circle out of segments.  Each push is along the
+// minimum translation vector of the segment that the circle overlaps the
+// most, so that a circle overlapping several segments is pushed out of the
+// deepest one first.
+func resolveCircle(c Circle, segs segmentSet) (Circle, Vector, bool) {
+	push := Vector{}
+	for i := 0; ; i++ {
+		depth, dir := deepestPenetration(c, segs)
+		if depth == 0 {
+			return c, push, true
+		}
+		if i == maxDepenetrations {
+			return c, push, false
+		}
+		v := dir.ScaledBy(depth + Threshold)
+		c.Center.Add(v)
+		push.Add(v)
+	}
+}
+
+// deepestPenetration returns the depth to which a circle overlaps the
+// segment that it overlaps the most, and the unit vector along which it
+// must be moved to no longer overlap that segment.  The depth is zero if
+// the circle does not overlap any segment.
+func deepestPenetration(c Circle, segs segmentSet) (float64, Vector) {
+	depth, dir := 0.0, Vector{}
+	segs.near(c.Bounds(), func(j int, s Segment) {
+		// Bodies are allowed to overlap one-way segments
+		// as they pass through them.
+		if segs.props(j).oneWay {
+			return
+		}
+		if d, n, ok := circlePenetration(c, s); ok && d > depth {
+			depth, dir = d, n
+		}
+	})
+	return depth, dir
+}
+
+// circlePenetration returns the depth to which a circle overlaps a segment,
+// and the unit vector along which it must be moved to no longer overlap it.
+// The last return value is false if the circle does not overlap the segment.
+func circlePenetration(c Circle, s Segment) (float64, Vector, bool) {
+	q := s.NearestPoint(c.Center)
+	d := c.Center.Minus(q)
+	dist := d.Magnitude()
+	if dist >= c.Radius {
+		return 0, Vector{}, false
+	}
+	if NearZero(dist) {
+		// The center is on the segment, so push out along its normal.
+		if degenerate(s) {
+			return c.Radius, Vector{0, 1}, true
+		}
+		return c.Radius, s.Normal(), true
+	}
+	return c.Radius - dist, d.ScaledBy(1 / dist), true
+}
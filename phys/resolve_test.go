@@ -0,0 +1,116 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"testing"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+func TestResolveCircleOverlap(t *testing.T) {
+	tests := []struct {
+		c    Circle
+		segs []Segment
+		push Vector
+		ok   bool
+	}{
+		{Circle{Center: Point{50, 50}, Radius: 10}, box, Vector{}, true},
+		{Circle{Center: Point{50, 5}, Radius: 10}, box, Vector{0, 5}, true},
+		{Circle{Center: Point{5, 5}, Radius: 10}, box, Vector{5, 5}, true},
+		// The center is on the segment.
+		{Circle{Center: Point{50, 0}, Radius: 10}, box[:1], Vector{0, 10}, true},
+		// The circle is too big to fit between the walls.
+		{Circle{Center: Point{50, 50}, Radius: 10}, []Segment{{{42, 0}, {42, 100}}, {{58, 100}, {58, 0}}}, Vector{}, false},
+	}
+	for _, test := range tests {
+		c, push, ok := ResolveCircleOverlap(test.c, test.segs)
+		if ok != test.ok {
+			t.Errorf("Expected resolving %v to be ok=%t, got %t", test.c, test.ok, ok)
+			continue
+		}
+		if !c.Center.NearlyEquals(test.c.Center.Plus(push)) {
+			t.Errorf("Expected resolving %v to move it by its push %v, got %v", test.c, push, c)
+		}
+		if ok && !push.NearlyEquals(test.push) {
+			t.Errorf("Expected %v to be pushed by %v, got %v", test.c, test.push, push)
+		}
+	}
+}
+
+func TestResolveCircleOverlapPushes(t *testing.T) {
+	// Deep in a V, a circle is pushed back and forth between its sides,
+	// taking more pushes the narrower the V.
+	tests := []struct {
+		w  float64
+		ok bool
+	}{
+		{10, true},
+		// Exactly maxDepenetrations pushes.
+		{8.75, true},
+		{8.5, false},
+		{6, false},
+	}
+	for _, test := range tests {
+		segs := vee(test.w)
+		c, _, ok := ResolveCircleOverlap(Circle{Center: Point{0, 1}, Radius: 1}, segs)
+		if ok != test.ok {
+			t.Errorf("Expected resolving in a V %g wide to be ok=%t, got %t", test.w, test.ok, ok)
+		}
+		if !ok {
+			continue
+		}
+		for _, s := range segs {
+			if _, _, over := circlePenetration(c, s); over {
+				t.Errorf("Expected resolving in a V %g wide to leave %v out of %v", test.w, c, s)
+			}
+		}
+	}
+}
+
+func TestResolveOverlap(t *testing.T) {
+	tests := []struct {
+		e    Ellipse
+		push Vector
+		ok   bool
+	}{
+		{Ellipse{Center: Point{50, 50}, Radii: Vector{10, 20}}, Vector{}, true},
+		{Ellipse{Center: Point{50, 15}, Radii: Vector{10, 20}}, Vector{0, 5}, true},
+		{Ellipse{Center: Point{95, 50}, Radii: Vector{10, 20}}, Vector{-5, 0}, true},
+		{Ellipse{Center: Point{50, 50}, Radii: Vector{60, 20}}, Vector{}, false},
+	}
+	for _, test := range tests {
+		e, push, ok := ResolveOverlap(test.e, box)
+		if ok != test.ok {
+			t.Errorf("Expected resolving %v to be ok=%t, got %t", test.e, test.ok, ok)
+			continue
+		}
+		// Each push goes a little further, by Threshold in the space
+		// of the unit circle, to be sure that it no longer overlaps.
+		if ok && push.Minus(test.push).Magnitude() > 1e-6 {
+			t.Errorf("Expected %v to be pushed by %v, got %v", test.e, test.push, push)
+		}
+		if ok && e.Center.Minus(test.e.Center.Plus(push)).Magnitude() > 1e-6 {
+			t.Errorf("Expected resolving %v to move it by its push %v, got %v", test.e, push, e)
+		}
+	}
+}
+
+func TestGridResolveOverlapOneWay(t *testing.T) {
+	// A body passing through the one-way ledge is left overlapping it,
+	// but is still pushed out of the floor.
+	g := ledgeGrid()
+	tests := []struct {
+		e    Ellipse
+		push Vector
+	}{
+		{Ellipse{Center: Point{100, 55}, Radii: Vector{10, 20}}, Vector{}},
+		{Ellipse{Center: Point{100, 15}, Radii: Vector{10, 20}}, Vector{0, 5}},
+	}
+	for _, test := range tests {
+		_, push, ok := g.ResolveOverlap(test.e)
+		if !ok || push.Minus(test.push).Magnitude() > 1e-6 {
+			t.Errorf("Expected %v to be pushed by %v, got %v, ok=%t", test.e, test.push, push, ok)
+		}
+	}
+}
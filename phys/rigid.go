@@ -0,0 +1,580 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"math"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+// DefaultIterations is the default Iterations of a World.
+const DefaultIterations = 10
+
+const (
+	// RigidSlop is the distance that rigid bodies may overlap
+	// before they are pushed apart.  Allowing a little overlap keeps
+	// resting contacts from flickering on and off.
+	rigidSlop = 0.01
+
+	// RigidCorrection is the fraction of the overlap beyond rigidSlop
+	// that is corrected at each step.
+	rigidCorrection = 0.2
+
+	// RigidBounceSpeed is the speed, along the normal of a contact,
+	// below which rigid bodies do not bounce.
+	rigidBounceSpeed = 1
+)
+
+// A RigidID identifies a rigid body in a World.
+type RigidID int
+
+// A RigidBody is a body that rotates as well as moves, such as a rolling
+// barrel, a tumbling crate or a seesaw.  Rigid bodies collide with the
+// static segments of a World, with its platforms, with its bodies and with
+// each other, and contacts push them apart with impulses that change both
+// their velocities and their angular velocities.
+//
+// Bodies that hit a rigid body push it, and rigid bodies push bodies
+// that have mass.  To a rigid body, a body with zero mass is an obstacle
+// that moves on its own, as if it had infinite mass.
+type RigidBody struct {
+	// Polygon is the shape of the body in its own space, in which
+	// the center of mass is at the origin.  It must be convex, but its
+	// vertices may be in either order.  If it is nil then the body is
+	// a circle.
+	Polygon Polygon
+
+	// Radius is the radius of a circular body.
+	Radius float64
+
+	// Position is the position of the center of mass in the world.
+	Position Point
+
+	// Angle is the counter-clockwise rotation of the body in radians.
+	Angle float64
+
+	// Velocity is the velocity of the center of mass in units per second.
+	Velocity Vector
+
+	// AngularVelocity is the counter-clockwise angular velocity of the
+	// body in radians per second.
+	AngularVelocity float64
+
+	// Mass is the mass of the body.  A body with zero mass is kinematic:
+	// it is not moved by gravity or by contacts, but only by its Velocity
+	// and AngularVelocity, and it pushes other bodies as if it had
+	// infinite mass.
+	Mass float64
+
+	// Inertia is the moment of inertia of the body about its center
+	// of mass.  A body with zero inertia does not rotate from contacts.
+	Inertia float64
+
+	// Pinned is true if the center of mass of the body is fixed in
+	// place, but the body is free to rotate about it, like a seesaw.
+	Pinned bool
+
+	// Material is the material of the body's surface.  Where two
+	// surfaces touch, the friction is the average of their Frictions,
+	// and the restitution is the greater of their Restitutions.
+	Material Material
+
+	// Filter is the filter of the body.
+	Filter Filter
+
+	// Update, if non-nil, is called for the body at the start of each
+	// step, before gravity is applied.
+	Update func(r *RigidBody, dt float64)
+
+	// PreviousPosition and PreviousAngle are the position and angle
+	// at the start of the most recent step.
+	PreviousPosition Point
+	PreviousAngle    float64
+
+	id RigidID
+
+	// World is the polygon of the body in the world, or nil for a circle.
+	world Polygon
+}
+
+// NewRigidCircle returns a new circular rigid body with the given mass,
+// and the moment of inertia of a uniform disc.
+func NewRigidCircle(c Circle, mass float64) *RigidBody {
+	return &RigidBody{
+		Radius:   c.Radius,
+		Position: c.Center,
+		Mass:     mass,
+		Inertia:  mass * c.Radius * c.Radius / 2,
+	}
+}
+
+// NewRigidPolygon returns a new rigid body covering a convex polygon in
+// the world, with the given mass, and the moment of inertia of a uniform
+// polygon.  The position of the body is the centroid of the polygon.
+func NewRigidPolygon(poly Polygon, mass float64) *RigidBody {
+	if !poly.CounterClockwise() {
+		poly = poly.Reverse()
+	}
+	c := poly.Centroid()
+	local := make(Polygon, len(poly))
+	for i, p := range poly {
+		local[i] = Point(p.Minus(c))
+	}
+	// The moment of inertia of each triangle from the centroid to an
+	// edge, weighted by its share of the area.
+	num, den := 0.0, 0.0
+	for i := range local {
+		e := local.Edge(i)
+		a, b := Vector(e[0]), Vector(e[1])
+		k := a.Cross(b)
+		num += k * (a.Dot(a) + a.Dot(b) + b.Dot(b))
+		den += k
+	}
+	inertia := 0.0
+	if den != 0 {
+		inertia = mass * num / (6 * den)
+	}
+	return &RigidBody{
+		Polygon:  local,
+		Position: c,
+		Mass:     mass,
+		Inertia:  inertia,
+	}
+}
+
+// NewRigidBox returns a new rigid body covering a rectangle in the world,
+// as for NewRigidPolygon.
+func NewRigidBox(r Rectangle, mass float64) *RigidBody {
+	return NewRigidPolygon(r.Canon().Polygon(), mass)
+}
+
+// ID returns the ID of the rigid body.  It is zero if the body has not
+// been added to a World.
+func (r *RigidBody) ID() RigidID {
+	return r.id
+}
+
+// Transform returns the transform from the body's space into the world.
+func (r *RigidBody) Transform() Transform {
+	return Rotate(r.Angle).Then(Translate(Vector(r.Position)))
+}
+
+// Interpolate returns the transform from the body's space into the world
+// a fraction alpha of the way from its position at the start of the most
+// recent step to its current position.  It is used with the return value
+// of Advance to draw rigid bodies smoothly between steps.
+func (r *RigidBody) Interpolate(alpha float64) Transform {
+	a, b := r.PreviousPosition, r.Position
+	p := a.Plus(b.Minus(a).ScaledBy(alpha))
+	theta := r.PreviousAngle + (r.Angle-r.PreviousAngle)*alpha
+	return Rotate(theta).Then(Translate(Vector(p)))
+}
+
+// WorldPolygon returns the polygon of the body in the world, with its
+// vertices in counter-clockwise order, or nil if the body is a circle.
+// The returned polygon must not be modified.
+func (r *RigidBody) WorldPolygon() Polygon {
+	return r.world
+}
+
+// place computes the world polygon of the body.  Its vertices are in
+// counter-clockwise order, as the collision code requires, whatever the
+// order of the vertices of Polygon.
+func (r *RigidBody) place() {
+	if r.Polygon == nil {
+		r.world = nil
+		return
+	}
+	tr := r.Transform()
+	r.world = make(Polygon, len(r.Polygon))
+	for i, p := range r.Polygon {
+		r.world[i] = tr.ApplyPoint(p)
+	}
+	if !r.world.CounterClockwise() {
+		r.world = r.world.Reverse()
+	}
+}
+
+// shape returns the convex shape of the body in the world.
+func (r *RigidBody) shape() convex {
+	if r.world == nil {
+		return convex{pts: []Point{r.Position}, radius: r.Radius}
+	}
+	return convex{pts: r.world}
+}
+
+// bounds returns the smallest rectangle containing the body.
+func (r *RigidBody) bounds() Rectangle {
+	if r.world == nil {
+		return Circle{Center: r.Position, Radius: r.Radius}.Bounds()
+	}
+	return r.world.Bounds()
+}
+
+// edges returns the edges of the body in the world, with normals pointing
+// out of the body.  Circles are approximated by polygons, as for bodies.
+func (r *RigidBody) edges() []Segment {
+	if r.world == nil {
+		e := Ellipse{Center: r.Position, Radii: Vector{r.Radius, r.Radius}}
+		return ellipsePolygon(e, bodySides).Edges()
+	}
+	return r.world.Edges()
+}
+
+// numEdges returns the number of edges returned by edges.
+func (r *RigidBody) numEdges() int {
+	if r.world == nil {
+		return bodySides
+	}
+	return len(r.world)
+}
+
+// invMass returns the inverse of the body's mass for linear motion.
+func (r *RigidBody) invMass() float64 {
+	if r.Mass <= 0 || r.Pinned {
+		return 0
+	}
+	return 1 / r.Mass
+}
+
+// invInertia returns the inverse of the body's moment of inertia.
+func (r *RigidBody) invInertia() float64 {
+	if r.Mass <= 0 || r.Inertia <= 0 {
+		return 0
+	}
+	return 1 / r.Inertia
+}
+
+// dynamic returns true if the body is moved by contacts.
+func (r *RigidBody) dynamic() bool {
+	return r.invMass() > 0 || r.invInertia() > 0
+}
+
+// pointVelocity returns the velocity of the point of the body
+// that is at a point in the world.
+func (r *RigidBody) pointVelocity(p Point) Vector {
+	d := p.Minus(r.Position)
+	return r.Velocity.Plus(Vector{-d[1], d[0]}.ScaledBy(r.AngularVelocity))
+}
+
+// applyImpulse applies an impulse to the body at a point in the world.
+func (r *RigidBody) applyImpulse(j Vector, p Point) {
+	r.Velocity = r.Velocity.Plus(j.ScaledBy(r.invMass()))
+	r.AngularVelocity += r.invInertia() * p.Minus(r.Position).Cross(j)
+}
+
+// AddRigid adds a rigid body to the world and returns its ID.  IDs are
+// assigned in increasing order and are never reused.
+func (w *World) AddRigid(r *RigidBody) RigidID {
+	w.nextRigid++
+	r.id = w.nextRigid
+	r.PreviousPosition, r.PreviousAngle = r.Position, r.Angle
+	r.place()
+	w.rigids = append(w.rigids, r)
+	return r.id
+}
+
+// RemoveRigid removes the rigid body with the given ID from the world.
+// Removing a rigid body that is not in the world does nothing.
+func (w *World) RemoveRigid(id RigidID) {
+	for i, r := range w.rigids {
+		if r.id == id {
+			w.rigids = append(w.rigids[:i], w.rigids[i+1:]...)
+			return
+		}
+	}
+}
+
+// Rigid returns the rigid body with the given ID, or nil if there is none.
+func (w *World) Rigid(id RigidID) *RigidBody {
+	for _, r := range w.rigids {
+		if r.id == id {
+			return r
+		}
+	}
+	return nil
+}
+
+// Rigids returns the rigid bodies of the world in the order that they
+// are stepped.  The returned slice must not be modified.
+func (w *World) Rigids() []*RigidBody {
+	return w.rigids
+}
+
+// stepRigids advances the rigid bodies by a step.  Gravity is applied,
+// then the contacts are found and resolved by applying impulses to the
+// bodies in several passes, and finally the bodies are moved.
+func (w *World) stepRigids(dt float64) {
+	for _, r := range append([]*RigidBody(nil), w.rigids...) {
+		if w.Rigid(r.id) != r {
+			continue
+		}
+		r.PreviousPosition, r.PreviousAngle = r.Position, r.Angle
+		if r.Update != nil {
+			r.Update(r, dt)
+		}
+		if r.invMass() > 0 {
+			r.Velocity = r.Velocity.Plus(w.Gravity.ScaledBy(dt))
+		}
+	}
+
+	cs := w.rigidContacts(dt)
+	for _, c := range cs {
+		c.prepare(dt)
+	}
+	n := w.Iterations
+	if n == 0 {
+		n = DefaultIterations
+	}
+	for i := 0; i < n; i++ {
+		for _, c := range cs {
+			c.solve()
+		}
+	}
+
+	for _, r := range w.rigids {
+		if r.Pinned && r.Mass > 0 {
+			r.Velocity = Vector{}
+		}
+		r.Position = r.Position.Plus(r.Velocity.ScaledBy(dt))
+		r.Angle += r.AngularVelocity * dt
+		r.place()
+	}
+}
+
+// rigidContacts returns the contacts of the rigid bodies with the static
+// segments, the platforms, the bodies, and each other.
+func (w *World) rigidContacts(dt float64) []*rigidContact {
+	var cs []*rigidContact
+	add := func(a, b *RigidBody, body *Body, vb func(Point) Vector, m manifold, mat Material) {
+		for i, p := range m.points {
+			c := &rigidContact{
+				a:           a,
+				b:           b,
+				body:        body,
+				point:       p,
+				normal:      m.normal,
+				depth:       m.depths[i],
+				friction:    (a.Material.Friction + mat.Friction) / 2,
+				restitution: math.Max(a.Material.Restitution, mat.Restitution),
+			}
+			if vb != nil {
+				c.vb = vb(p)
+			}
+			cs = append(cs, c)
+		}
+	}
+	// Segment collides a rigid body with a segment that moves with a
+	// velocity given by vb.
+	segment := func(r *RigidBody, s Segment, props segmentProps, vb func(Point) Vector) {
+		if degenerate(s) {
+			return
+		}
+		m, ok := collideConvex(convex{pts: s[:]}, r.shape())
+		if !ok {
+			return
+		}
+		// One-way segments only hold up bodies that are in front of them.
+		if props.oneWay && (m.normal.Dot(s.Normal()) <= 0 || !inFront(r.Position, 0, s)) {
+			return
+		}
+		surface := s[1].Minus(s[0]).Unit().ScaledBy(props.material.SurfaceVelocity)
+		add(r, nil, nil, func(p Point) Vector {
+			if vb == nil {
+				return surface
+			}
+			return vb(p).Plus(surface)
+		}, m, props.material)
+	}
+
+	for i, r := range w.rigids {
+		box := r.bounds().Expand(rigidSlop)
+		if r.dynamic() {
+			if w.Grid != nil {
+				fs := filteredSet{segs: w.Grid, f: r.Filter}
+				fs.near(box, func(j int, s Segment) {
+					segment(r, s, w.Grid.props(j), nil)
+				})
+			}
+			for _, p := range w.platforms {
+				if !p.Filter.Collides(r.Filter) {
+					continue
+				}
+				v := platformVelocity(p, dt)
+				for _, s := range p.world {
+					if s.Bounds().Intersects(box) {
+						segment(r, s, p.props(), v)
+					}
+				}
+			}
+			for _, b := range w.bodies {
+				if !b.Options.Filter.Collides(r.Filter) || !b.Ellipse.Bounds().Intersects(box) {
+					continue
+				}
+				if m, ok := collideConvex(bodyShape(b), r.shape()); ok {
+					add(r, nil, b, nil, m, Material{})
+				}
+			}
+		}
+		for _, o := range w.rigids[i+1:] {
+			if !r.dynamic() && !o.dynamic() || !r.Filter.Collides(o.Filter) || !o.bounds().Intersects(box) {
+				continue
+			}
+			if m, ok := collideConvex(o.shape(), r.shape()); ok {
+				add(r, o, nil, nil, m, o.Material)
+			}
+		}
+	}
+	return cs
+}
+
+// platformVelocity returns a function giving the velocity, during the
+// most recent step, of the point of a platform that is now at a point.
+func platformVelocity(p *Platform, dt float64) func(Point) Vector {
+	inv, ok := p.Transform.Inverse()
+	if !ok {
+		return nil
+	}
+	return func(pt Point) Vector {
+		prev := p.Previous.ApplyPoint(inv.ApplyPoint(pt))
+		return pt.Minus(prev).ScaledBy(1 / dt)
+	}
+}
+
+// bodyShape returns the convex shape of a body, which is approximated
+// by a polygon unless it is a circle.
+func bodyShape(b *Body) convex {
+	if circular(b.Ellipse) {
+		return convex{pts: []Point{b.Ellipse.Center}, radius: b.Ellipse.Radii[0]}
+	}
+	return convex{pts: ellipsePolygon(b.Ellipse, bodySides)}
+}
+
+// A rigidContact is a point of contact between a rigid body and either
+// another rigid body or an obstacle that is moved independently.
+type rigidContact struct {
+	a *RigidBody
+
+	// B is the other rigid body, or nil if the contact is with a body
+	// or an obstacle.  Body is the body, or nil if the contact is not
+	// with a body.  Vb is the velocity of an obstacle, which is not
+	// moved by the contact, including a body with zero mass.
+	b    *RigidBody
+	body *Body
+	vb   Vector
+
+	// Normal is the unit normal pointing from b toward a.
+	point  Point
+	normal Vector
+	depth  float64
+
+	friction, restitution float64
+
+	// MassN and massT are the effective masses along the normal and
+	// the tangent, and bias is the target velocity along the normal.
+	massN, massT, bias float64
+
+	// Jn and jt are the accumulated impulses along the normal and
+	// the tangent.
+	jn, jt float64
+}
+
+// prepare computes the effective masses and the target velocity
+// of the contact.
+func (c *rigidContact) prepare(dt float64) {
+	n := c.normal
+	t := Vector{n[1], -n[0]}
+	kn, kt := c.a.effectiveMass(c.point, n), c.a.effectiveMass(c.point, t)
+	if c.b != nil {
+		kn += c.b.effectiveMass(c.point, n)
+		kt += c.b.effectiveMass(c.point, t)
+	}
+	if c.body != nil && c.body.Mass > 0 {
+		kn += 1 / c.body.Mass
+		kt += 1 / c.body.Mass
+	}
+	if kn > 0 {
+		c.massN = 1 / kn
+	}
+	if kt > 0 {
+		c.massT = 1 / kt
+	}
+	c.bias = rigidCorrection / dt * math.Max(0, c.depth-rigidSlop)
+	if vn := c.relativeVelocity().Dot(n); vn < -rigidBounceSpeed {
+		c.bias = math.Max(c.bias, -c.restitution*vn)
+	}
+}
+
+// effectiveMass returns the inverse of the mass of the body, as seen by
+// an impulse along a unit vector at a point.
+func (r *RigidBody) effectiveMass(p Point, n Vector) float64 {
+	rn := p.Minus(r.Position).Cross(n)
+	return r.invMass() + r.invInertia()*rn*rn
+}
+
+// relativeVelocity returns the velocity of a relative to b at the contact.
+func (c *rigidContact) relativeVelocity() Vector {
+	vb := c.vb
+	switch {
+	case c.b != nil:
+		vb = c.b.pointVelocity(c.point)
+	case c.body != nil:
+		vb = c.body.Velocity
+	}
+	return c.a.pointVelocity(c.point).Minus(vb)
+}
+
+// solve applies impulses to the bodies to stop them approaching along
+// the normal and to resist their sliding with friction.  The accumulated
+// impulse along the normal only ever pushes the bodies apart, and friction
+// is limited by it.
+func (c *rigidContact) solve() {
+	n := c.normal
+	vn := c.relativeVelocity().Dot(n)
+	jn := math.Max(c.jn+c.massN*(c.bias-vn), 0)
+	c.apply(n.ScaledBy(jn - c.jn))
+	c.jn = jn
+
+	t := Vector{n[1], -n[0]}
+	vt := c.relativeVelocity().Dot(t)
+	max := c.friction * c.jn
+	jt := Clamp(c.jt-c.massT*vt, -max, max)
+	c.apply(t.ScaledBy(jt - c.jt))
+	c.jt = jt
+}
+
+// apply applies an impulse to a and the opposite impulse to b or to
+// the body.
+func (c *rigidContact) apply(j Vector) {
+	c.a.applyImpulse(j, c.point)
+	if c.b != nil {
+		c.b.applyImpulse(j.Inverse(), c.point)
+	}
+	if c.body != nil && c.body.Mass > 0 {
+		c.body.Velocity = c.body.Velocity.Minus(j.ScaledBy(1 / c.body.Mass))
+	}
+}
+
+// pushRigid pushes a rigid body with body a, which hit it at a point with
+// the given collision normal, pointing from the rigid body toward a.  The
+// velocities along the normal at the point are made equal, as after an
+// inelastic collision.
+func pushRigid(a *Body, r *RigidBody, p Point, n Vector) {
+	vn := a.Velocity.Minus(r.pointVelocity(p)).Dot(n)
+	if vn >= 0 {
+		return
+	}
+	k := r.effectiveMass(p, n)
+	if a.Mass > 0 {
+		k += 1 / a.Mass
+	}
+	if k <= 0 {
+		return
+	}
+	j := -vn / k
+	r.applyImpulse(n.ScaledBy(-j), p)
+	if a.Mass > 0 {
+		a.Velocity = a.Velocity.Plus(n.ScaledBy(j / a.Mass))
+	}
+}
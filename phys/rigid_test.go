@@ -0,0 +1,283 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+// rigidWorld returns a world of the box room with downward gravity.
+func rigidWorld(segs []Segment) *World {
+	w := NewWorld(NewGrid(segs, 20), dt)
+	w.Gravity = Vector{0, -500}
+	return w
+}
+
+// stepWorld steps a world n times.
+func stepWorld(w *World, n int) {
+	for i := 0; i < n; i++ {
+		w.Step()
+	}
+}
+
+func TestRigidRest(t *testing.T) {
+	square := NewRectangle(Point{40, 40}, Point{60, 60})
+	tests := []struct {
+		name string
+		r    *RigidBody
+	}{
+		{"circle", NewRigidCircle(Circle{Center: Point{50, 50}, Radius: 10}, 1)},
+		{"box", NewRigidBox(square, 1)},
+		{"tilted box", func() *RigidBody {
+			r := NewRigidBox(square, 1)
+			r.Angle = 0.3
+			return r
+		}()},
+		{"clockwise box", &RigidBody{
+			Polygon:  square.Polygon().Reverse(),
+			Position: Point{0, 0},
+			Mass:     1,
+			Inertia:  NewRigidBox(square, 1).Inertia,
+		}},
+		{"triangle", NewRigidPolygon(Polygon{{40, 40}, {60, 40}, {50, 60}}, 1)},
+	}
+	for _, test := range tests {
+		w := rigidWorld(box)
+		r := test.r
+		if r.Polygon != nil && r.Position == (Point{}) {
+			// Center the body built directly at the center of the room.
+			r.Position = Point{50, 50}
+			for i, p := range r.Polygon {
+				r.Polygon[i] = Point(p.Minus(square.Center()))
+			}
+		}
+		w.AddRigid(r)
+		stepWorld(w, 300)
+		if b := r.bounds(); math.Abs(b.Min[1]) > 2*rigidSlop {
+			t.Errorf("Expected the %s to rest on the floor, its bottom is at %g", test.name, b.Min[1])
+		}
+		if v := r.Velocity.Magnitude(); v > 0.1 || math.Abs(r.AngularVelocity) > 0.01 {
+			t.Errorf("Expected the %s to come to rest, velocity %v, angular velocity %g",
+				test.name, r.Velocity, r.AngularVelocity)
+		}
+	}
+}
+
+func TestRigidRestitution(t *testing.T) {
+	tests := []struct {
+		restitution float64
+		bounce      bool
+	}{
+		{0, false},
+		{0.5, true},
+		{0.9, true},
+	}
+	for _, test := range tests {
+		w := rigidWorld(box)
+		r := NewRigidCircle(Circle{Center: Point{50, 80}, Radius: 5}, 1)
+		r.Material.Restitution = test.restitution
+		w.AddRigid(r)
+		down, up := 0.0, 0.0
+		for i := 0; i < 60; i++ {
+			w.Step()
+			down = math.Min(down, r.Velocity[1])
+			up = math.Max(up, r.Velocity[1])
+		}
+		// The overlap after a fast hit is corrected by a small
+		// upward velocity, even without restitution.
+		if bounce := up > -down/4; bounce != test.bounce {
+			t.Errorf("Expected restitution %g to bounce=%t, got a speed of %g down and %g up",
+				test.restitution, test.bounce, -down, up)
+		}
+	}
+}
+
+func TestRigidFriction(t *testing.T) {
+	slope := append([]Segment{{{0, 60}, {100, 10}}}, box...)
+	tests := []struct {
+		friction float64
+		// roll is true if the barrel rolls without slipping.
+		roll bool
+	}{
+		{0, false},
+		{1, true},
+	}
+	for _, test := range tests {
+		w := rigidWorld(slope)
+		w.Grid.SetMaterial(0, Material{Friction: test.friction})
+		r := NewRigidCircle(Circle{Center: Point{10, 70}, Radius: 5}, 1)
+		r.Material.Friction = test.friction
+		w.AddRigid(r)
+		stepWorld(w, 30)
+		speed, spin := r.Velocity.Magnitude(), -r.AngularVelocity*r.Radius
+		if speed < 10 {
+			t.Fatalf("Expected the barrel to move down the slope, got velocity %v", r.Velocity)
+		}
+		if roll := math.Abs(speed-spin) < 0.05*speed; roll != test.roll {
+			t.Errorf("Expected friction %g to roll=%t, got speed %g and spin %g",
+				test.friction, test.roll, speed, spin)
+		}
+	}
+}
+
+func TestRigidSeesaw(t *testing.T) {
+	w := rigidWorld(box)
+	plank := NewRigidBox(NewRectangle(Point{10, 20}, Point{90, 24}), 2)
+	plank.Pinned = true
+	w.AddRigid(plank)
+	w.AddRigid(NewRigidBox(NewRectangle(Point{75, 40}, Point{85, 50}), 1))
+	stepWorld(w, 60)
+	if plank.Angle > -0.05 {
+		t.Errorf("Expected the plank to tip down to the right, got angle %g", plank.Angle)
+	}
+	if p := (Point{50, 22}); !plank.Position.NearlyEquals(p) {
+		t.Errorf("Expected the plank to stay pinned at %v, got %v", p, plank.Position)
+	}
+}
+
+func TestRigidKinematic(t *testing.T) {
+	w := rigidWorld(box)
+	k := NewRigidBox(NewRectangle(Point{30, 40}, Point{70, 44}), 0)
+	k.Velocity = Vector{0, 6}
+	k.AngularVelocity = 0.5
+	w.AddRigid(k)
+	ball := NewRigidCircle(Circle{Center: Point{50, 60}, Radius: 5}, 1)
+	w.AddRigid(ball)
+	stepWorld(w, 60)
+	if p := (Point{50, 48}); !k.Position.NearlyEquals(p) || !NearEqual(k.Angle, 0.5) {
+		t.Errorf("Expected the kinematic body to move only by its velocities, got %v, angle %g", k.Position, k.Angle)
+	}
+}
+
+func TestRigidBodies(t *testing.T) {
+	// A body with mass landing on a crate rests on it.
+	w := rigidWorld(box)
+	crate := NewRigidBox(NewRectangle(Point{30, 0}, Point{70, 20}), 5)
+	w.AddRigid(crate)
+	b := &Body{Ellipse: Ellipse{Center: Point{50, 60}, Radii: Vector{5, 10}}, Mass: 1}
+	w.Add(b)
+	stepWorld(w, 120)
+	// The body is held up by correcting its overlap with the crate,
+	// so it rests a little way into it.
+	if b.Err != nil || math.Abs(b.Ellipse.Center[1]-30) > 1 {
+		t.Errorf("Expected the body to rest on the crate at 30, got %v, %v", b.Ellipse.Center, b.Err)
+	}
+	if math.Abs(crate.Position[1]-10) > 2*rigidSlop {
+		t.Errorf("Expected the crate to rest on the floor at 10, got %v", crate.Position)
+	}
+
+	// A body pushes a crate along the floor.
+	w = rigidWorld(box)
+	crate = NewRigidBox(NewRectangle(Point{30, 0}, Point{40, 10}), 1)
+	w.AddRigid(crate)
+	b = &Body{Ellipse: Ellipse{Center: Point{20, 10}, Radii: Vector{5, 10}}}
+	w.Add(b)
+	for i := 0; i < 30; i++ {
+		b.Velocity[0] = 20
+		w.Step()
+	}
+	if crate.Position[0] < 40 {
+		t.Errorf("Expected the body to push the crate, got %v", crate.Position)
+	}
+	if b.Ellipse.Center[0] > crate.Position[0]-10 {
+		t.Errorf("Expected the body to stay behind the crate, got %v and %v", b.Ellipse.Center, crate.Position)
+	}
+}
+
+func TestRigidDeterministic(t *testing.T) {
+	run := func() []Point {
+		w := rigidWorld(box)
+		var rs []*RigidBody
+		for i := 0; i < 4; i++ {
+			x, y := 40+float64(i), float64(i)*12
+			r := NewRigidBox(NewRectangle(Point{x, y}, Point{x + 15, y + 10}), 1)
+			r.Material.Friction = 0.5
+			rs = append(rs, r)
+			w.AddRigid(r)
+		}
+		stepWorld(w, 300)
+		var ps []Point
+		for _, r := range rs {
+			ps = append(ps, r.Position)
+		}
+		return ps
+	}
+	a, b := run(), run()
+	for i := range a {
+		if a[i] != b[i] {
+			t.Errorf("Expected the same positions from each run, got %v and %v", a, b)
+			break
+		}
+	}
+	if top := a[len(a)-1][1]; top < 30 {
+		t.Errorf("Expected the stack to stand, the top box is at %g", top)
+	}
+}
+
+func TestCollideConvex(t *testing.T) {
+	square := NewRectangle(Point{0, 0}, Point{10, 10}).Polygon()
+	tests := []struct {
+		a, b   convex
+		hit    bool
+		normal Vector
+		depth  float64
+		points int
+	}{
+		{
+			convex{pts: []Point{{0, 0}}, radius: 5}, convex{pts: []Point{{8, 0}}, radius: 5},
+			true, Vector{1, 0}, 2, 1,
+		},
+		{
+			convex{pts: []Point{{0, 0}}, radius: 5}, convex{pts: []Point{{10, 0}}, radius: 5},
+			false, Vector{}, 0, 0,
+		},
+		{
+			convex{pts: square}, convex{pts: []Point{{5, 12}}, radius: 3},
+			true, Vector{0, 1}, 1, 1,
+		},
+		{
+			convex{pts: []Point{{5, 12}}, radius: 3}, convex{pts: square},
+			true, Vector{0, -1}, 1, 1,
+		},
+		// The center of the circle is inside of the polygon.
+		{
+			convex{pts: square}, convex{pts: []Point{{5, 9}}, radius: 2},
+			true, Vector{0, 1}, 3, 1,
+		},
+		{
+			convex{pts: square}, convex{pts: NewRectangle(Point{2, 9}, Point{8, 19}).Polygon()},
+			true, Vector{0, 1}, 1, 2,
+		},
+		{
+			convex{pts: square}, convex{pts: NewRectangle(Point{11, 0}, Point{21, 10}).Polygon()},
+			false, Vector{}, 0, 0,
+		},
+		// A segment and a box resting on it.
+		{
+			convex{pts: []Point{{-10, 0}, {20, 0}}}, convex{pts: NewRectangle(Point{2, -1}, Point{8, 9}).Polygon()},
+			true, Vector{0, 1}, 1, 2,
+		},
+	}
+	for _, test := range tests {
+		m, hit := collideConvex(test.a, test.b)
+		if hit != test.hit {
+			t.Errorf("Expected %v and %v to collide=%t, got %t", test.a, test.b, test.hit, hit)
+			continue
+		}
+		if !hit {
+			continue
+		}
+		if !m.normal.NearlyEquals(test.normal) || len(m.points) != test.points {
+			t.Errorf("Expected %v and %v to collide along %v at %d points, got %v at %v",
+				test.a, test.b, test.normal, test.points, m.normal, m.points)
+		}
+		for _, d := range m.depths {
+			if !NearEqual(d, test.depth) {
+				t.Errorf("Expected %v and %v to overlap by %g, got %v", test.a, test.b, test.depth, m.depths)
+			}
+		}
+	}
+}
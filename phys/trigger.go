@@ -0,0 +1,175 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"fmt"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+// A TriggerID identifies a trigger in a World.
+type TriggerID int
+
+// A Trigger is a region of a World that does not block bodies, but that
+// reports when bodies begin to overlap it, continue to overlap it, and stop
+// overlapping it.  Checkpoints, kill zones and level exits are triggers.
+//
+// Triggers are checked at the end of each step, after all of the bodies
+// have moved, in increasing order of their IDs.  For each trigger, the
+// events are reported in increasing order of the IDs of the bodies.
+type Trigger struct {
+	// Region is the region covered by the trigger.
+	Region Region
+
+	// Filter is the filter of the trigger.  Only bodies whose filters
+	// collide with it set off the trigger.
+	Filter Filter
+
+	// Enter, if non-nil, is called when a body begins to overlap the trigger.
+	Enter func(t *Trigger, b *Body)
+
+	// Stay, if non-nil, is called at each step for each body that
+	// continues to overlap the trigger.
+	Stay func(t *Trigger, b *Body)
+
+	// Exit, if non-nil, is called when a body stops overlapping the
+	// trigger.  A body that is removed from the world exits the triggers
+	// that it overlapped at the next step.
+	Exit func(t *Trigger, b *Body)
+
+	id TriggerID
+
+	// Inside are the bodies overlapping the trigger, in increasing
+	// order of their IDs.
+	inside []*Body
+}
+
+// ID returns the ID of the trigger.  It is zero if the trigger has not
+// been added to a World.
+func (t *Trigger) ID() TriggerID {
+	return t.id
+}
+
+// Bodies returns the bodies that overlapped the trigger as of the most
+// recent step, in increasing order of their IDs.  The returned slice must
+// not be modified.
+func (t *Trigger) Bodies() []*Body {
+	return t.inside
+}
+
+// AddTrigger adds a trigger to the world and returns its ID.  IDs are
+// assigned in increasing order and are never reused.  The trigger's Region
+// must not be nil.
+func (w *World) AddTrigger(t *Trigger) TriggerID {
+	if t.Region == nil {
+		panic("Trigger region must not be nil")
+	}
+	w.nextTrigger++
+	t.id = w.nextTrigger
+	t.inside = nil
+	w.triggers = append(w.triggers, t)
+	return t.id
+}
+
+// RemoveTrigger removes the trigger with the given ID from the world.
+// Removing a trigger that is not in the world does nothing.  No events
+// are reported for the bodies that overlapped a removed trigger.
+func (w *World) RemoveTrigger(id TriggerID) {
+	for i, t := range w.triggers {
+		if t.id == id {
+			w.triggers = append(w.triggers[:i], w.triggers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Trigger returns the trigger with the given ID, or nil if there is none.
+func (w *World) Trigger(id TriggerID) *Trigger {
+	for _, t := range w.triggers {
+		if t.id == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// Triggers returns the triggers of the world in the order that they are
+// checked.  The returned slice must not be modified.
+func (w *World) Triggers() []*Trigger {
+	return w.triggers
+}
+
+// checkTrigger finds the bodies overlapping a trigger and reports the
+// bodies that entered, stayed in, and exited it.
+func (w *World) checkTrigger(t *Trigger) {
+	var inside []*Body
+	for _, b := range w.bodies {
+		if t.Filter.Collides(b.Options.Filter) && overlaps(b.Ellipse, t.Region) {
+			inside = append(inside, b)
+		}
+	}
+	old := t.inside
+	t.inside = inside
+
+	// Both lists are in order of ID, so merge them.
+	for len(old) > 0 || len(inside) > 0 {
+		switch {
+		case len(inside) == 0 || len(old) > 0 && old[0].id < inside[0].id:
+			if t.Exit != nil {
+				t.Exit(t, old[0])
+			}
+			old = old[1:]
+		case len(old) == 0 || inside[0].id < old[0].id:
+			if t.Enter != nil {
+				t.Enter(t, inside[0])
+			}
+			inside = inside[1:]
+		default:
+			if t.Stay != nil {
+				t.Stay(t, inside[0])
+			}
+			old, inside = old[1:], inside[1:]
+		}
+	}
+}
+
+// overlaps returns true if an ellipse overlaps a region.  Shapes that
+// only touch do not overlap.
+func overlaps(e Ellipse, r Region) bool {
+	if _, ok := e.Bounds().Intersection(r.Bounds()); !ok {
+		return false
+	}
+	// The test is done in the space where the ellipse is a unit circle.
+	tr, _ := unitCircleSpace(e)
+	c := tr.ApplyPoint(e.Center)
+	near := func(p Point) bool { return p.SquaredDistance(c) < 1 }
+	// The transform is a scale, so it maps ellipses to ellipses.
+	ellipse := func(o Ellipse) Ellipse {
+		return Ellipse{Center: tr.ApplyPoint(o.Center), Radii: tr.ApplyVector(o.Radii)}
+	}
+
+	switch r := r.(type) {
+	case Rectangle:
+		return near(tr.ApplyRectangle(r).Clamp(c))
+	case Circle:
+		return near(ellipse(Ellipse{Center: r.Center, Radii: Vector{r.Radius, r.Radius}}).NearestPoint(c))
+	case Ellipse:
+		return near(ellipse(r).NearestPoint(c))
+	case Polygon:
+		poly := make(Polygon, len(r))
+		for i, p := range r {
+			poly[i] = tr.ApplyPoint(p)
+		}
+		if poly.ContainsNonZero(c) {
+			return true
+		}
+		for i := range poly {
+			if near(poly.Edge(i).NearestPoint(c)) {
+				return true
+			}
+		}
+		return false
+	}
+	panic(fmt.Sprintf("unsupported region type %T", r))
+}
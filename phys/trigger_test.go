@@ -0,0 +1,157 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+// eventTrigger returns a trigger with the given region that appends
+// its events to a slice.
+func eventTrigger(r Region, events *[]string) *Trigger {
+	event := func(kind string) func(*Trigger, *Body) {
+		return func(_ *Trigger, b *Body) {
+			*events = append(*events, fmt.Sprintf("%s %d", kind, b.ID()))
+		}
+	}
+	return &Trigger{Region: r, Enter: event("enter"), Stay: event("stay"), Exit: event("exit")}
+}
+
+func TestTriggerEvents(t *testing.T) {
+	w := NewWorld(nil, dt)
+	var events []string
+	trig := eventTrigger(NewRectangle(Point{40, 0}, Point{60, 100}), &events)
+	w.AddTrigger(trig)
+	// Body 1 moves through the trigger, and body 2 stays in it.
+	w.Add(&Body{Ellipse: Ellipse{Center: Point{20, 50}, Radii: Vector{4, 4}}, Velocity: Vector{10 / dt, 0}})
+	w.Add(&Body{Ellipse: Ellipse{Center: Point{50, 20}, Radii: Vector{4, 4}}})
+	want := [][]string{
+		{"enter 2"},
+		{"enter 1", "stay 2"},
+		{"stay 1", "stay 2"},
+		{"stay 1", "stay 2"},
+		{"exit 1", "stay 2"},
+		{"stay 2"},
+	}
+	for i, wnt := range want {
+		events = nil
+		w.Step()
+		if !reflect.DeepEqual(events, wnt) {
+			t.Errorf("Expected step %d to report %v, got %v", i+1, wnt, events)
+		}
+		// The bodies inside are those that entered or stayed.
+		n := 0
+		for _, e := range wnt {
+			if !strings.HasPrefix(e, "exit") {
+				n++
+			}
+		}
+		if len(trig.Bodies()) != n {
+			t.Errorf("Expected %d bodies in the trigger after step %d, got %d", n, i+1, len(trig.Bodies()))
+		}
+	}
+}
+
+func TestTriggerRemoveBody(t *testing.T) {
+	w := NewWorld(nil, dt)
+	var events []string
+	w.AddTrigger(eventTrigger(NewRectangle(Point{0, 0}, Point{100, 100}), &events))
+	id := w.Add(&Body{Ellipse: Ellipse{Center: Point{50, 50}, Radii: Vector{4, 4}}})
+	w.Step()
+	w.Remove(id)
+	events = nil
+	w.Step()
+	if want := []string{fmt.Sprintf("exit %d", id)}; !reflect.DeepEqual(events, want) {
+		t.Errorf("Expected a removed body to exit the trigger, got %v", events)
+	}
+	events = nil
+	w.Step()
+	if len(events) != 0 {
+		t.Errorf("Expected no more events for a removed body, got %v", events)
+	}
+}
+
+func TestAddTriggerNilRegion(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected adding a trigger without a region to panic")
+		}
+	}()
+	NewWorld(nil, dt).AddTrigger(&Trigger{})
+}
+
+func TestOverlaps(t *testing.T) {
+	e := Ellipse{Center: Point{0, 0}, Radii: Vector{2, 1}}
+	tests := []struct {
+		r        Region
+		overlaps bool
+	}{
+		{NewRectangle(Point{1.5, -0.5}, Point{2.5, 0.5}), true},
+		{NewRectangle(Point{-10, -10}, Point{10, 10}), true},
+		{NewRectangle(Point{-0.5, -0.5}, Point{0.5, 0.5}), true},
+		// Only touching.
+		{NewRectangle(Point{2, -1}, Point{3, 1}), false},
+		{NewRectangle(Point{-1, 1}, Point{1, 2}), false},
+		{NewRectangle(Point{1.9, 0.9}, Point{3, 2}), false},
+		{NewRectangle(Point{5, 5}, Point{6, 6}), false},
+
+		{Circle{Center: Point{3, 0}, Radius: 1.1}, true},
+		{Circle{Center: Point{3, 0}, Radius: 0.9}, false},
+		{Circle{Center: Point{0, 0}, Radius: 10}, true},
+		{Circle{Center: Point{0, 0}, Radius: 0.1}, true},
+
+		{Ellipse{Center: Point{0, 1.9}, Radii: Vector{1, 1}}, true},
+		{Ellipse{Center: Point{0, 2.1}, Radii: Vector{1, 1}}, false},
+		{Ellipse{Center: Point{3, 0}, Radii: Vector{1.1, 5}}, true},
+		{Ellipse{Center: Point{3, 0}, Radii: Vector{0.9, 5}}, false},
+
+		{Polygon{{1.5, -1}, {3, -1}, {3, 1}, {1.5, 1}}, true},
+		{Polygon{{-1, -1}, {1, -1}, {0, 1}}, true},
+		// The ellipse is inside of the polygon, in either order.
+		{Polygon{{-5, -5}, {5, -5}, {0, 5}}, true},
+		{Polygon{{0, 5}, {5, -5}, {-5, -5}}, true},
+		// Only touching.
+		{Polygon{{2, -1}, {3, -1}, {3, 1}, {2, 1}}, false},
+		{Polygon{{5, 5}, {6, 5}, {6, 6}}, false},
+	}
+	for _, test := range tests {
+		if o := overlaps(e, test.r); o != test.overlaps {
+			t.Errorf("Expected %v to overlap %v=%t, got %t", e, test.r, test.overlaps, o)
+		}
+	}
+}
+
+func TestTriggerRegions(t *testing.T) {
+	regions := []Region{
+		NewRectangle(Point{40, 40}, Point{60, 60}),
+		Circle{Center: Point{50, 50}, Radius: 10},
+		Ellipse{Center: Point{50, 50}, Radii: Vector{10, 5}},
+		Polygon{{40, 40}, {60, 40}, {50, 60}},
+	}
+	for _, r := range regions {
+		w := NewWorld(nil, dt)
+		var events []string
+		w.AddTrigger(eventTrigger(r, &events))
+		w.Add(&Body{Ellipse: Ellipse{Center: Point{20, 50}, Radii: Vector{4, 4}}, Velocity: Vector{5 / dt, 0}})
+		stepWorld(w, 16)
+		if want := []string{"enter 1", "stay 1", "exit 1"}; !reflect.DeepEqual(compact(events), want) {
+			t.Errorf("Expected a body moving through %v to enter, stay in and exit it, got %v", r, events)
+		}
+	}
+}
+
+// compact returns the events without repeats.
+func compact(events []string) []string {
+	var c []string
+	for _, e := range events {
+		if len(c) == 0 || c[len(c)-1] != e {
+			c = append(c, e)
+		}
+	}
+	return c
+}
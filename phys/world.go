@@ -0,0 +1,499 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"math"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+// DefaultMaxSteps is the default MaxSteps of a World.
+const DefaultMaxSteps = 8
+
+// A World is a set of moving bodies that collide with static segments
+// and with moving platforms, and that set off triggers, along with rigid
+// bodies that also rotate.
+//
+// A world advances in steps of a fixed duration, so that a simulation
+// gives the same results regardless of the frame rate at which it is
+// drawn.  Within a step, the platforms are moved first, then the bodies,
+// then the rigid bodies, and then the triggers are checked, each in
+// increasing order of their IDs, so the results are reproducible from
+// run to run.
+type World struct {
+	// Grid holds the static segments of the world.
+	Grid *Grid
+
+	// Timestep is the duration of a single step in seconds.
+	Timestep float64
+
+	// Gravity is the acceleration due to gravity.
+	Gravity Vector
+
+	// MaxSteps is the maximum number of steps taken by a single call
+	// to Advance.  If advancing would take more steps, then the excess
+	// time is dropped, slowing down the simulation instead of letting it
+	// fall further and further behind.  If MaxSteps is zero then
+	// DefaultMaxSteps is used.
+	MaxSteps int
+
+	// Iterations is the number of passes made over the contacts of
+	// the rigid bodies at each step.  More passes give more accurate
+	// results, for example for stacks of rigid bodies, but take longer.
+	// If Iterations is zero then DefaultIterations is used.
+	Iterations int
+
+	// Collides, if non-nil, reports whether a pair of bodies, whose
+	// filters collide, block each other.  Bodies that do not block each
+	// other pass through each other.  If Collides is nil then all bodies
+	// with colliding filters block each other.
+	Collides func(a, b *Body) bool
+
+	// Bodies are in increasing order of their IDs.
+	bodies []*Body
+	ids    map[BodyID]*Body
+	nextID BodyID
+
+	// Platforms are in increasing order of their IDs.
+	platforms    []*Platform
+	nextPlatform PlatformID
+
+	// Triggers are in increasing order of their IDs.
+	triggers    []*Trigger
+	nextTrigger TriggerID
+
+	// Rigids are in increasing order of their IDs.
+	rigids    []*RigidBody
+	nextRigid RigidID
+
+	// Acc is the amount of time that has elapsed but has not yet
+	// been simulated.
+	acc float64
+}
+
+// A BodyID identifies a body in a World.
+type BodyID int
+
+// A Body is an elliptical body that moves in a World.
+//
+// Bodies collide with the static segments of the world and with each
+// other.  When a body moves, the other bodies are treated as stationary
+// obstacles; a body that is hit is then pushed by giving it some of the
+// velocity of the body that hit it, according to their masses.
+type Body struct {
+	// Ellipse is the shape and position of the body.  A circular body
+	// is an ellipse with equal radii.
+	Ellipse Ellipse
+
+	// Velocity is the velocity of the body in units per second.
+	Velocity Vector
+
+	// Options are the options used to move the body.  If the Up
+	// option is zero, then up is opposite to the world's gravity.
+	// The Filter option determines the segments and the other
+	// bodies that the body collides with.
+	Options MoveOptions
+
+	// Mass is the mass of the body, used when bodies push each other.
+	// A body with zero mass is immovable: it is not pushed by other bodies,
+	// and it pushes them as if it had infinite mass.
+	Mass float64
+
+	// Update, if non-nil, is called for the body at the start of each
+	// step, after gravity is applied and before the body is moved.  It
+	// can be used to control the body, for example, by setting its
+	// velocity from player input.
+	Update func(b *Body, dt float64)
+
+	// Contacts are the contacts made during the most recent step.
+	Contacts []Contact
+
+	// Err is the error from moving the body during the most recent step,
+	// as described for MoveEllipse.
+	Err error
+
+	// Crushed is true if the body was squeezed between a platform and
+	// another obstacle during the most recent step.
+	Crushed bool
+
+	// Previous is the ellipse at the start of the most recent step.
+	Previous Ellipse
+
+	id BodyID
+
+	// Ride is the ID of the platform that carried the body during the
+	// current step, and rideVelocity is the velocity of the platform.
+	// A body that leaves a platform keeps the platform's velocity.
+	ride         PlatformID
+	rideVelocity Vector
+
+	// Pushes are the contacts made by platforms pushing the body
+	// during the current step.
+	pushes []Contact
+}
+
+// NewWorld returns a new world with the given static segments and timestep.
+func NewWorld(g *Grid, timestep float64) *World {
+	if timestep <= 0 {
+		panic("World timestep must be positive")
+	}
+	return &World{
+		Grid:     g,
+		Timestep: timestep,
+		ids:      make(map[BodyID]*Body),
+	}
+}
+
+// Add adds a body to the world and returns its ID.  IDs are assigned in
+// increasing order and are never reused.
+func (w *World) Add(b *Body) BodyID {
+	w.nextID++
+	b.id = w.nextID
+	b.Previous = b.Ellipse
+	w.bodies = append(w.bodies, b)
+	w.ids[b.id] = b
+	return b.id
+}
+
+// Remove removes the body with the given ID from the world.
+// Removing a body that is not in the world does nothing.
+func (w *World) Remove(id BodyID) {
+	if _, ok := w.ids[id]; !ok {
+		return
+	}
+	delete(w.ids, id)
+	for i, b := range w.bodies {
+		if b.id == id {
+			w.bodies = append(w.bodies[:i], w.bodies[i+1:]...)
+			break
+		}
+	}
+}
+
+// Body returns the body with the given ID, or nil if there is none.
+func (w *World) Body(id BodyID) *Body {
+	return w.ids[id]
+}
+
+// Bodies returns the bodies of the world in the order that they are
+// stepped.  The returned slice must not be modified.
+func (w *World) Bodies() []*Body {
+	return w.bodies
+}
+
+// Advance advances the world by an amount of elapsed time in seconds,
+// taking as many steps as fit within the elapsed time plus any time left
+// over from previous calls.  The return value is the fraction of a step
+// that is left over, between 0 and 1, which can be used to interpolate
+// the bodies for drawing.
+func (w *World) Advance(elapsed float64) float64 {
+	max := w.MaxSteps
+	if max == 0 {
+		max = DefaultMaxSteps
+	}
+	w.acc += elapsed
+	for n := 0; w.acc >= w.Timestep; n++ {
+		if n == max {
+			w.acc = 0
+			break
+		}
+		w.Step()
+		w.acc -= w.Timestep
+	}
+	return w.acc / w.Timestep
+}
+
+// Step advances the world by a single timestep.
+func (w *World) Step() {
+	dt := w.Timestep
+	for _, b := range w.bodies {
+		b.Previous = b.Ellipse
+		b.Crushed = false
+		b.ride = 0
+		b.pushes = b.pushes[:0]
+	}
+	// Platforms and bodies may be added or removed by Update,
+	// so iterate over copies.
+	for _, p := range append([]*Platform(nil), w.platforms...) {
+		if w.Platform(p.id) == p {
+			w.stepPlatform(p, dt)
+		}
+	}
+	for _, b := range append([]*Body(nil), w.bodies...) {
+		if w.ids[b.id] == b {
+			w.stepBody(b, dt)
+		}
+	}
+	w.stepRigids(dt)
+	for _, t := range append([]*Trigger(nil), w.triggers...) {
+		if w.Trigger(t.id) == t {
+			w.checkTrigger(t)
+		}
+	}
+}
+
+// stepBody moves a body by its velocity.
+func (w *World) stepBody(b *Body, dt float64) {
+	b.Velocity = b.Velocity.Plus(w.Gravity.ScaledBy(dt))
+	if b.Update != nil {
+		b.Update(b, dt)
+	}
+	set := &worldSet{w: w, mover: b}
+	var cs []Contact
+	b.Ellipse, cs, b.Err = moveEllipse(b.Ellipse, b.Velocity.ScaledBy(dt), set, w.options(b))
+	for i := range cs {
+		o, _, r := set.owner(&cs[i])
+		switch {
+		case o != nil && o.Mass > 0:
+			push(b, o, cs[i].Normal)
+		case r != nil && r.dynamic():
+			pushRigid(b, r, cs[i].Point, cs[i].Normal)
+		default:
+			b.Velocity = cs[i].velocity(b.Velocity)
+		}
+	}
+	b.Contacts = append(append(b.Contacts[:0], b.pushes...), cs...)
+	if b.ride != 0 {
+		if g, ok := GroundContact(b.Contacts); !ok || g.Platform != b.ride {
+			b.Velocity = b.Velocity.Plus(b.rideVelocity)
+		}
+	}
+}
+
+// options returns the options used to move a body.
+func (w *World) options(b *Body) MoveOptions {
+	opts := b.Options
+	if opts.Up.NearZero() {
+		opts.Up = w.Gravity.ScaledBy(-1)
+	}
+	return opts.withDefaults()
+}
+
+// push pushes body o with body a, which hit it with the given collision
+// normal, pointing from o toward a.  The bodies' velocities along the
+// normal are set to their common velocity after an inelastic collision.
+func push(a, o *Body, n Vector) {
+	va, vo := -a.Velocity.Dot(n), -o.Velocity.Dot(n)
+	if va <= vo {
+		return
+	}
+	v := va
+	if a.Mass > 0 {
+		v = (a.Mass*va + o.Mass*vo) / (a.Mass + o.Mass)
+	}
+	a.Velocity = a.Velocity.Plus(n.ScaledBy(va - v))
+	o.Velocity = o.Velocity.Minus(n.ScaledBy(v - vo))
+}
+
+// collides returns true if two bodies block each other.
+func (w *World) collides(a, b *Body) bool {
+	if !a.Options.Filter.Collides(b.Options.Filter) {
+		return false
+	}
+	return w.Collides == nil || w.Collides(a, b)
+}
+
+// A worldSet is the set of obstacles for a body moving in a world: the
+// static segments, the other bodies, the platforms, and the rigid bodies.
+// The indices of the segments are the same as in the grid.  They are
+// followed by the bodies, in the order of the world's bodies, then by the
+// segments of each platform, in the order of the world's platforms, and
+// then by the edges of each rigid body, in the order of the world's
+// rigid bodies.
+//
+// Circular bodies and rigid bodies are circles to a circular mover, so
+// that collisions between them are exact.  Otherwise they are approximated
+// by polygons.
+type worldSet struct {
+	w     *World
+	mover *Body
+
+	// Exclude, if non-nil, is a platform that is not in the set.
+	exclude *Platform
+}
+
+// bodySides is the number of sides of the polygons approximating bodies.
+const bodySides = 16
+
+// numSegments returns the number of static segments in the world.
+func (ws *worldSet) numSegments() int {
+	if ws.w.Grid == nil {
+		return 0
+	}
+	return len(ws.w.Grid.segs)
+}
+
+func (ws *worldSet) near(r Rectangle, f func(int, Segment)) {
+	if ws.w.Grid != nil {
+		ws.mover.Options.filtered(ws.w.Grid).near(r, f)
+	}
+	n := ws.numSegments()
+	round := circular(ws.mover.Ellipse)
+	ws.others(r, func(i int, o *Body) {
+		if round && circular(o.Ellipse) {
+			return
+		}
+		for _, s := range ellipsePolygon(o.Ellipse, bodySides).Edges() {
+			f(n+i, s)
+		}
+	})
+	ws.platforms(func(base int, p *Platform) {
+		platformSet{p, p.world}.near(r, func(i int, s Segment) {
+			f(base+i, s)
+		})
+	})
+	ws.rigids(func(base int, o *RigidBody) {
+		if !o.bounds().Intersects(r) || round && o.world == nil {
+			return
+		}
+		for i, s := range o.edges() {
+			f(base+i, s)
+		}
+	})
+}
+
+func (ws *worldSet) props(i int) segmentProps {
+	if i < ws.numSegments() {
+		return ws.w.Grid.props(i)
+	}
+	var props segmentProps
+	ws.platforms(func(base int, p *Platform) {
+		if i >= base && i < base+len(p.world) {
+			props = p.props()
+		}
+	})
+	ws.rigids(func(base int, r *RigidBody) {
+		if i >= base && i < base+r.numEdges() {
+			props = segmentProps{filter: r.Filter, material: r.Material}
+		}
+	})
+	return props
+}
+
+// platforms calls a function with each platform that blocks the mover,
+// along with the index of its first segment.
+func (ws *worldSet) platforms(f func(int, *Platform)) {
+	base := ws.numSegments() + len(ws.w.bodies)
+	for _, p := range ws.w.platforms {
+		if p != ws.exclude && p.Filter.Collides(ws.mover.Options.Filter) {
+			f(base, p)
+		}
+		base += len(p.world)
+	}
+}
+
+// rigids calls a function with each rigid body that blocks the mover,
+// along with the index of its first edge.
+func (ws *worldSet) rigids(f func(int, *RigidBody)) {
+	base := ws.numSegments() + len(ws.w.bodies)
+	for _, p := range ws.w.platforms {
+		base += len(p.world)
+	}
+	for _, r := range ws.w.rigids {
+		if r.Filter.Collides(ws.mover.Options.Filter) {
+			f(base, r)
+		}
+		base += r.numEdges()
+	}
+}
+
+// owner sets the Segment, Body, Platform, and Rigid fields of a contact
+// made by the mover, given the contact's index in the set, and it returns
+// the body, platform, or rigid body that was hit, if any.
+func (ws *worldSet) owner(c *Contact) (*Body, *Platform, *RigidBody) {
+	n := ws.numSegments()
+	if c.Segment < n {
+		return nil, nil, nil
+	}
+	if i := c.Segment - n; i < len(ws.w.bodies) {
+		o := ws.w.bodies[i]
+		c.Segment, c.Body = -1, o.id
+		return o, nil, nil
+	}
+	var hit *Platform
+	var rigid *RigidBody
+	i := c.Segment
+	ws.platforms(func(base int, p *Platform) {
+		if i >= base && i < base+len(p.world) {
+			hit = p
+			c.Segment = i - base
+			c.Platform = p.id
+		}
+	})
+	ws.rigids(func(base int, r *RigidBody) {
+		if i >= base && i < base+r.numEdges() {
+			rigid = r
+			c.Segment, c.Rigid = -1, r.id
+		}
+	})
+	return nil, hit, rigid
+}
+
+func (ws *worldSet) nearCircles(r Rectangle, f func(int, Circle)) {
+	if !circular(ws.mover.Ellipse) {
+		return
+	}
+	n := ws.numSegments()
+	ws.others(r, func(i int, o *Body) {
+		if circular(o.Ellipse) {
+			f(n+i, Circle{Center: o.Ellipse.Center, Radius: o.Ellipse.Radii[0]})
+		}
+	})
+	ws.rigids(func(base int, o *RigidBody) {
+		if o.world == nil && o.bounds().Intersects(r) {
+			f(base, Circle{Center: o.Position, Radius: o.Radius})
+		}
+	})
+}
+
+// others calls a function with each body, other than the mover, that
+// blocks the mover and may intersect a rectangle, along with its index.
+func (ws *worldSet) others(r Rectangle, f func(int, *Body)) {
+	for i, o := range ws.w.bodies {
+		if o == ws.mover || !ws.w.collides(ws.mover, o) {
+			continue
+		}
+		// Expand the bounds to cover the approximating polygon.
+		b := o.Ellipse.Bounds().Expand(o.Ellipse.Radii[0] + o.Ellipse.Radii[1])
+		if b.Intersects(r) {
+			f(i, o)
+		}
+	}
+}
+
+// circular returns true if the ellipse is a circle.
+func circular(e Ellipse) bool {
+	return e.Radii[0] == e.Radii[1]
+}
+
+// ellipsePolygon returns a polygon with n sides that contains an ellipse.
+func ellipsePolygon(e Ellipse, n int) Polygon {
+	// The vertices are pushed out so that the edges, rather than
+	// the vertices, touch the ellipse.
+	k := 1 / math.Cos(math.Pi/float64(n))
+	poly := make(Polygon, n)
+	for i := range poly {
+		theta := 2 * math.Pi * float64(i) / float64(n)
+		poly[i] = Point{
+			e.Center[0] + k*e.Radii[0]*math.Cos(theta),
+			e.Center[1] + k*e.Radii[1]*math.Sin(theta),
+		}
+	}
+	return poly
+}
+
+// ID returns the ID of the body.  It is zero if the body has not
+// been added to a World.
+func (b *Body) ID() BodyID {
+	return b.id
+}
+
+// Interpolate returns the ellipse of the body a fraction alpha of the way
+// from its position at the start of the most recent step to its current
+// position.  It is used with the return value of Advance to draw bodies
+// smoothly between steps.
+func (b *Body) Interpolate(alpha float64) Ellipse {
+	d := b.Ellipse.Center.Minus(b.Previous.Center)
+	return Ellipse{Center: b.Previous.Center.Plus(d.ScaledBy(alpha)), Radii: b.Ellipse.Radii}
+}
@@ -0,0 +1,306 @@
+// © 2012 the Quart Authors under the MIT license. See AUTHORS for the list of authors.
+
+package phys
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/eaburns/quart/geom"
+)
+
+// countSteps returns a world with a timestep of a quarter second and
+// a body that counts the steps taken.
+func countSteps() (*World, *int) {
+	w := NewWorld(nil, 0.25)
+	n := 0
+	w.Add(&Body{
+		Ellipse: Ellipse{Center: Point{50, 50}, Radii: Vector{5, 5}},
+		Update:  func(*Body, float64) { n++ },
+	})
+	return w, &n
+}
+
+func TestWorldAdvance(t *testing.T) {
+	tests := []struct {
+		maxSteps int
+		elapsed  []float64
+		// steps and alpha are the total number of steps taken and
+		// the alpha returned after each call to Advance.
+		steps []int
+		alpha []float64
+	}{
+		{0, []float64{0.25}, []int{1}, []float64{0}},
+		{0, []float64{0.5}, []int{2}, []float64{0}},
+		{0, []float64{0.6}, []int{2}, []float64{0.4}},
+		// Left over time accumulates.
+		{0, []float64{0.125, 0.125, 0.125}, []int{0, 1, 1}, []float64{0.5, 0, 0.5}},
+		{0, []float64{0.2, 0.2, 0.2}, []int{0, 1, 2}, []float64{0.8, 0.6, 0.4}},
+		// Beyond MaxSteps, the excess time is dropped.
+		{0, []float64{10 * 0.25}, []int{DefaultMaxSteps}, []float64{0}},
+		{2, []float64{1, 0.125}, []int{2, 2}, []float64{0, 0.5}},
+		{2, []float64{0.875}, []int{2}, []float64{0}},
+		{2, []float64{0.5, 0.625}, []int{2, 4}, []float64{0, 0.5}},
+	}
+	for _, test := range tests {
+		w, n := countSteps()
+		w.MaxSteps = test.maxSteps
+		for i, e := range test.elapsed {
+			alpha := w.Advance(e)
+			if *n != test.steps[i] || math.Abs(alpha-test.alpha[i]) > 1e-9 {
+				t.Errorf("Expected advancing by %v with MaxSteps=%d to take %d steps with alpha %g after %g, got %d, %g",
+					test.elapsed, test.maxSteps, test.steps[i], test.alpha[i], e, *n, alpha)
+				break
+			}
+		}
+	}
+}
+
+func TestBodyInterpolate(t *testing.T) {
+	w := NewWorld(nil, 0.25)
+	b := &Body{Ellipse: Ellipse{Center: Point{10, 20}, Radii: Vector{5, 10}}, Velocity: Vector{40, -8}}
+	w.Add(b)
+	if e := b.Interpolate(0.5); e != b.Ellipse {
+		t.Errorf("Expected a body that has not moved to interpolate to %v, got %v", b.Ellipse, e)
+	}
+	w.Step()
+	tests := []struct {
+		alpha float64
+		e     Ellipse
+	}{
+		{0, Ellipse{Center: Point{10, 20}, Radii: Vector{5, 10}}},
+		{0.5, Ellipse{Center: Point{15, 19}, Radii: Vector{5, 10}}},
+		{1, Ellipse{Center: Point{20, 18}, Radii: Vector{5, 10}}},
+	}
+	for _, test := range tests {
+		if e := b.Interpolate(test.alpha); !e.Center.NearlyEquals(test.e.Center) || e.Radii != test.e.Radii {
+			t.Errorf("Expected the body interpolated by %g to be %v, got %v", test.alpha, test.e, e)
+		}
+	}
+}
+
+func TestWorldUpdateAddRemove(t *testing.T) {
+	w := NewWorld(nil, 0.25)
+	var stepped []BodyID
+	add := func() *Body {
+		b := &Body{Ellipse: Ellipse{Center: Point{float64(len(w.Bodies())) * 100, 0}, Radii: Vector{5, 5}}}
+		b.Update = func(b *Body, _ float64) { stepped = append(stepped, b.ID()) }
+		w.Add(b)
+		return b
+	}
+	b1, b2, b3, b4 := add(), add(), add(), add()
+	b1.Update = func(b *Body, _ float64) {
+		stepped = append(stepped, b.ID())
+		// Remove a later body, twice, and add a new one.
+		w.Remove(b3.ID())
+		w.Remove(b3.ID())
+		add()
+	}
+	b2.Update = func(b *Body, _ float64) {
+		stepped = append(stepped, b.ID())
+		w.Remove(b1.ID())
+	}
+	b4.Update = func(b *Body, _ float64) {
+		stepped = append(stepped, b.ID())
+		// Remove itself.
+		w.Remove(b.ID())
+	}
+
+	w.Step()
+	// Body 2 removed body 1 after it was stepped, body 1 removed body 3
+	// before it was stepped, and body 4 removed itself.  Body 5 was added
+	// during the step, so it is first stepped in the next one.
+	want := []BodyID{1, 2, 4}
+	if !equalIDs(stepped, want) {
+		t.Errorf("Expected the first step to update bodies %v, got %v", want, stepped)
+	}
+	if w.Body(1) != nil || w.Body(2) == nil || w.Body(3) != nil || w.Body(4) != nil || w.Body(5) == nil {
+		t.Errorf("Expected bodies 2 and 5 to remain, got %v", w.Bodies())
+	}
+
+	stepped = nil
+	w.Step()
+	want = []BodyID{2, 5}
+	if !equalIDs(stepped, want) {
+		t.Errorf("Expected the second step to update bodies %v, got %v", want, stepped)
+	}
+}
+
+func equalIDs(a, b []BodyID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestWorldDeterministic(t *testing.T) {
+	run := func() []Ellipse {
+		w := NewWorld(NewGrid(box, 20), dt)
+		w.Gravity = Vector{0, -500}
+		for i := 0; i < 8; i++ {
+			x := float64(i)
+			w.Add(&Body{
+				Ellipse:  Ellipse{Center: Point{15 + 10*x, 20 + 9*x}, Radii: Vector{4, 4 + x}},
+				Velocity: Vector{200 - 50*x, 10 * x},
+				Mass:     1 + x,
+			})
+		}
+		stepWorld(w, 120)
+		var es []Ellipse
+		for _, b := range w.Bodies() {
+			es = append(es, b.Ellipse)
+		}
+		return es
+	}
+	a, b := run(), run()
+	for i := range a {
+		if a[i] != b[i] {
+			t.Errorf("Expected body %d to end in the same place each run, got %v and %v", i+1, a[i], b[i])
+		}
+	}
+}
+
+// bodyPair returns a world without gravity and two bodies of the given
+// shapes and masses: one to the left, moving right at 10 units per step,
+// and one at rest to the right of it.
+func bodyPair(a, b Ellipse, ma, mb float64) (*World, *Body, *Body) {
+	w := NewWorld(nil, dt)
+	left := &Body{Ellipse: a, Velocity: Vector{10 / dt, 0}, Mass: ma}
+	right := &Body{Ellipse: b, Mass: mb}
+	w.Add(left)
+	w.Add(right)
+	return w, left, right
+}
+
+// hitBody returns the contact made by a body with another body, if any.
+func hitBody(a, b *Body) (Contact, bool) {
+	for _, c := range a.Contacts {
+		if c.Body == b.ID() {
+			return c, true
+		}
+	}
+	return Contact{}, false
+}
+
+func TestBodyBlocks(t *testing.T) {
+	circle := Ellipse{Center: Point{40, 50}, Radii: Vector{5, 5}}
+	tall := Ellipse{Center: Point{40, 50}, Radii: Vector{5, 10}}
+	tests := []struct {
+		a, b Ellipse
+		// gap is the greatest distance left between the bodies.
+		// Circles hit each other exactly, but other bodies are
+		// approximated by polygons with 16 sides that contain them.
+		gap float64
+	}{
+		{Ellipse{Center: Point{25, 50}, Radii: Vector{5, 5}}, circle, 1e-6},
+		{Ellipse{Center: Point{25, 50}, Radii: Vector{5, 5}}, tall, 0.2},
+		{Ellipse{Center: Point{25, 50}, Radii: Vector{5, 8}}, circle, 0.2},
+		{Ellipse{Center: Point{25, 50}, Radii: Vector{5, 8}}, tall, 0.2},
+	}
+	for _, test := range tests {
+		w, a, b := bodyPair(test.a, test.b, 1, 0)
+		w.Step()
+		if _, ok := hitBody(a, b); !ok {
+			t.Errorf("Expected %v to hit %v, got %v", test.a, test.b, a.Contacts)
+			continue
+		}
+		gap := b.Ellipse.Center[0] - b.Ellipse.Radii[0] - (a.Ellipse.Center[0] + a.Ellipse.Radii[0])
+		if gap < 0 || gap > test.gap {
+			t.Errorf("Expected %v to stop at most %g from %v, got %g", test.a, test.gap, test.b, gap)
+		}
+		if !a.Velocity.NearZero() || !b.Velocity.NearZero() || b.Ellipse != test.b {
+			t.Errorf("Expected %v to be stopped by the immovable %v, got %v and %v", test.a, test.b, a.Velocity, b)
+		}
+	}
+}
+
+func TestBodyPush(t *testing.T) {
+	const v = 10 / dt
+	tests := []struct {
+		ma, mb float64
+		// va and vb are the velocities of the bodies after the push.
+		va, vb float64
+	}{
+		{1, 1, v / 2, v / 2},
+		{3, 1, v * 3 / 4, v * 3 / 4},
+		{1, 3, v / 4, v / 4},
+		// A body with zero mass is immovable, and pushes as
+		// if its mass were infinite.
+		{0, 1, v, v},
+		{1, 0, 0, 0},
+		{0, 0, 0, 0},
+	}
+	for _, test := range tests {
+		a := Ellipse{Center: Point{25, 50}, Radii: Vector{5, 5}}
+		w, left, right := bodyPair(a, Ellipse{Center: Point{40, 50}, Radii: Vector{5, 5}}, test.ma, test.mb)
+		w.Step()
+		if _, ok := hitBody(left, right); !ok {
+			t.Errorf("Expected a body of mass %g to hit one of mass %g, got %v", test.ma, test.mb, left.Contacts)
+			continue
+		}
+		if !left.Velocity.NearlyEquals(Vector{test.va, 0}) || !right.Velocity.NearlyEquals(Vector{test.vb, 0}) {
+			t.Errorf("Expected a body of mass %g pushing one of mass %g to leave them at %g and %g, got %v and %v",
+				test.ma, test.mb, test.va, test.vb, left.Velocity, right.Velocity)
+		}
+	}
+}
+
+func TestBodyOnBody(t *testing.T) {
+	for _, mass := range []float64{0, 1} {
+		w := NewWorld(NewGrid(box, 20), dt)
+		w.Gravity = Vector{0, -500}
+		bottom := &Body{Ellipse: Ellipse{Center: Point{50, 10 + Threshold}, Radii: Vector{10, 10}}, Mass: mass}
+		top := &Body{Ellipse: Ellipse{Center: Point{50, 40}, Radii: Vector{5, 5}}, Mass: 1}
+		w.Add(bottom)
+		w.Add(top)
+		stepWorld(w, 60)
+		g, ok := GroundContact(top.Contacts)
+		if !ok || g.Body != bottom.ID() {
+			t.Errorf("Expected a body to stand on one of mass %g, got %v", mass, top.Contacts)
+			continue
+		}
+		if y := bottom.Ellipse.Center[1] + 15; math.Abs(top.Ellipse.Center[1]-y) > 1e-3 {
+			t.Errorf("Expected a body standing on one of mass %g to be at %g, got %v", mass, y, top.Ellipse)
+		}
+		if math.Abs(bottom.Ellipse.Center[1]-10) > 1e-3 {
+			t.Errorf("Expected a body of mass %g to stay on the floor, got %v", mass, bottom.Ellipse)
+		}
+	}
+}
+
+func TestWorldCollides(t *testing.T) {
+	a := Ellipse{Center: Point{25, 50}, Radii: Vector{5, 5}}
+	b := Ellipse{Center: Point{40, 50}, Radii: Vector{5, 8}}
+	for _, blocks := range []bool{true, false} {
+		w, left, right := bodyPair(a, b, 1, 1)
+		var calls int
+		w.Collides = func(x, y *Body) bool {
+			if x != left && x != right || y != left && y != right || x == y {
+				t.Errorf("Expected Collides to be called with the two bodies, got %v and %v", x, y)
+			}
+			calls++
+			return blocks
+		}
+		hit := false
+		for i := 0; i < 3; i++ {
+			w.Step()
+			_, ok := hitBody(left, right)
+			hit = hit || ok
+		}
+		if calls == 0 || hit != blocks {
+			t.Errorf("Expected bodies to block each other=%t, got %t after %d calls", blocks, hit, calls)
+		}
+		if blocks {
+			continue
+		}
+		// The bodies pass through each other.
+		if x := a.Center[0] + 30; math.Abs(left.Ellipse.Center[0]-x) > 1e-6 || right.Ellipse != b {
+			t.Errorf("Expected bodies to pass through each other, got %v and %v", left.Ellipse, right.Ellipse)
+		}
+	}
+}